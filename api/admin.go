@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// AdminPath is default prefix of admin endpoints
+	AdminPath = "/admin"
+	// VersionPath is path of build info endpoint within admin group
+	VersionPath = "/version"
+
+	adminLoggerParam = "logger"
+)
+
+// AdminConfig is configuration of admin endpoints
+type AdminConfig struct {
+	// Name is service name reported by version endpoint
+	Name string
+	// Version is service version reported by version endpoint
+	Version string
+	// Auth is middleware authenticating admin requests (required)
+	Auth echo.MiddlewareFunc
+	// Logger is root logger which level can be changed at runtime
+	Logger logger.Logger
+	// Loggers are named loggers which level can be changed separately
+	Loggers map[string]logger.Logger
+	// AllowPublic allows Server to serve admin endpoints on public listener if admin address is not set
+	// (they are not served there by default)
+	AllowPublic bool
+}
+
+// AdminBasicAuth returns middleware authenticating admin requests with basic auth credentials
+func AdminBasicAuth(username, password string) echo.MiddlewareFunc {
+	return middleware.BasicAuth(func(u, p string, _ echo.Context) (bool, error) {
+		userOK := subtle.ConstantTimeCompare([]byte(u), []byte(username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		return userOK && passOK, nil
+	})
+}
+
+// RegisterAdminRoutes adds admin endpoints to group:
+// net/http/pprof profiles, runtime.MemStats, log level and build info
+func RegisterAdminRoutes(g *echo.Group, cfg AdminConfig) error {
+	if cfg.Auth == nil {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			"admin endpoints should be protected by auth middleware")
+	}
+	g.Use(cfg.Auth)
+
+	g.GET("/debug/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+	g.GET("/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	g.GET("/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	g.GET("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.POST("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.GET("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	g.GET("/debug/pprof/:profile", pprofHandler)
+
+	g.GET("/memstats", memStatsHandler)
+	g.GET(VersionPath, versionHandler(cfg.Name, cfg.Version))
+
+	loggers := newLogLevels(cfg)
+	g.GET("/loglevel", loggers.list)
+	g.GET(fmt.Sprintf("/loglevel/:%s", adminLoggerParam), loggers.get)
+	g.PUT("/loglevel", loggers.set)
+	g.PUT(fmt.Sprintf("/loglevel/:%s", adminLoggerParam), loggers.set)
+	return nil
+}
+
+func pprofHandler(c echo.Context) error {
+	pprof.Handler(c.Param("profile")).ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+func memStatsHandler(c echo.Context) error {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return c.JSON(http.StatusOK, ms)
+}
+
+func versionHandler(name, version string) echo.HandlerFunc {
+	resp := BuildInfoResponse{
+		Name:      name,
+		Version:   version,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		resp.Module = bi.Main.Path
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				resp.Revision = s.Value
+			case "vcs.time":
+				resp.RevisionTime = s.Value
+			case "vcs.modified":
+				resp.Modified = s.Value == "true"
+			}
+		}
+		for _, dep := range bi.Deps {
+			mi := ModuleInfo{Path: dep.Path, Version: dep.Version}
+			if dep.Replace != nil {
+				mi.Replace = dep.Replace.Path + "@" + dep.Replace.Version
+			}
+			resp.Deps = append(resp.Deps, mi)
+		}
+	}
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// logLevels manages levels of root and named loggers
+type logLevels struct {
+	loggers map[string]logger.Logger
+}
+
+func newLogLevels(cfg AdminConfig) *logLevels {
+	l := &logLevels{loggers: make(map[string]logger.Logger, len(cfg.Loggers)+1)}
+	for name, lgr := range cfg.Loggers {
+		l.loggers[name] = lgr
+	}
+	if cfg.Logger != nil {
+		l.loggers[""] = cfg.Logger
+	}
+	return l
+}
+
+func (l *logLevels) list(c echo.Context) error {
+	names := make([]string, 0, len(l.loggers))
+	for name := range l.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	levels := make([]LogLevel, 0, len(names))
+	for _, name := range names {
+		levels = append(levels, LogLevel{Logger: name, Level: logger.ParseLevel(l.loggers[name].GetLevel())})
+	}
+	return c.JSON(http.StatusOK, levels)
+}
+
+func (l *logLevels) get(c echo.Context) error {
+	name := c.Param(adminLoggerParam)
+	lgr, ok := l.loggers[name]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("logger %s not found", name))
+	}
+	return c.JSON(http.StatusOK, LogLevel{Logger: name, Level: logger.ParseLevel(lgr.GetLevel())})
+}
+
+func (l *logLevels) set(c echo.Context) error {
+	var req LogLevel
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	name := c.Param(adminLoggerParam)
+	lgr, ok := l.loggers[name]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("logger %s not found", name))
+	}
+	level := strings.ToLower(req.Level)
+	if logger.ParseLevel(logger.ToLogLevel(level)) != level {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown log level %s", req.Level))
+	}
+	if err := lgr.SetLevel(logger.ToLogLevel(level)); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, LogLevel{Logger: name, Level: level})
+}
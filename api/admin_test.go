@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	log := logger.NewNopLogger()
+	e := echo.New()
+	err := api.RegisterAdminRoutes(e.Group(api.AdminPath), api.AdminConfig{
+		Name:    "svc",
+		Version: "1.0.0",
+		Auth:    api.AdminBasicAuth("admin", "secret"),
+		Logger:  log,
+	})
+	if err != nil {
+		t.Fatalf("RegisterAdminRoutes returned error: %v", err)
+	}
+	do := func(method, path, body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, api.AdminPath+path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should require auth middleware", func(t *testing.T) {
+		if err := api.RegisterAdminRoutes(e.Group("/other"), api.AdminConfig{}); err == nil {
+			t.Error("RegisterAdminRoutes should fail without auth")
+		}
+	})
+	t.Run("should reject unauthenticated requests", func(t *testing.T) {
+		if rec := do(http.MethodGet, api.VersionPath, "", false); rec.Code != http.StatusUnauthorized {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+	t.Run("should return build info", func(t *testing.T) {
+		rec := do(http.MethodGet, api.VersionPath, "", true)
+		var resp api.BuildInfoResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal returned error: %v", err)
+		}
+		if resp.Name != "svc" || resp.Version != "1.0.0" || resp.GoVersion == "" {
+			t.Errorf("got %+v, expected name, version and go version", resp)
+		}
+	})
+	t.Run("should serve pprof profiles and memstats", func(t *testing.T) {
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/memstats"} {
+			if rec := do(http.MethodGet, path, "", true); rec.Code != http.StatusOK {
+				t.Errorf("%s: got %d, expected %d", path, rec.Code, http.StatusOK)
+			}
+		}
+	})
+	t.Run("should change log level", func(t *testing.T) {
+		if rec := do(http.MethodPut, "/loglevel", `{"level":"debug"}`, true); rec.Code != http.StatusOK {
+			t.Fatalf("got %d, expected %d", rec.Code, http.StatusOK)
+		}
+		if log.GetLevel() != logger.DebugLevel {
+			t.Errorf("got %s, expected debug", logger.ParseLevel(log.GetLevel()))
+		}
+		if rec := do(http.MethodPut, "/loglevel", `{"level":"verbose"}`, true); rec.Code != http.StatusBadRequest {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusBadRequest)
+		}
+		if rec := do(http.MethodGet, "/loglevel/unknown", "", true); rec.Code != http.StatusNotFound {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// DefaultMaxBodySize is default maximum size of request body accepted by BindJSON
+const DefaultMaxBodySize int64 = 1 << 20
+
+// BindJSON decodes JSON request body (up to DefaultMaxBodySize) into v and validates it (see Validate)
+func BindJSON(ctx echo.Context, v interface{}) error {
+	return BindJSONWithLimit(ctx, v, DefaultMaxBodySize)
+}
+
+// BindJSONWithLimit decodes JSON request body (up to maxSize bytes) into v and validates it (see Validate),
+// unknown fields are rejected
+func BindJSONWithLimit(ctx echo.Context, v interface{}, maxSize int64) error {
+	if ct := GetContentType(ctx); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != echo.MIMEApplicationJSON {
+			return apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("unsupported content type %q, expected %s", ct, echo.MIMEApplicationJSON))
+		}
+	}
+	if GetContentSize(ctx) > maxSize {
+		return errPayloadTooLarge(maxSize)
+	}
+
+	req := ctx.Request()
+	dec := json.NewDecoder(http.MaxBytesReader(ctx.Response(), req.Body, maxSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err, maxSize)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err, maxSize)
+		}
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			"request body should contain a single JSON value")
+	}
+
+	return Validate(v)
+}
+
+// decodeError converts json decoding error to AppError
+func decodeError(err error, maxSize int64) error {
+	var (
+		maxBytesErr  *http.MaxBytesError
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		unknownField = "json: unknown field "
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return errPayloadTooLarge(maxSize)
+	case errors.Is(err, io.EOF):
+		return apperrors.NewAppError(apperrors.ErrorDataValidation, "request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apperrors.NewAppError(apperrors.ErrorDataValidation, "request body is not valid JSON (unexpected end of input)")
+	case errors.As(err, &syntaxErr):
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("request body is not valid JSON (at offset %d)", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		return apperrors.NewValidationError("request body does not match expected schema",
+			[]apperrors.FieldError{{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("should be %s", typeErr.Type.Kind()),
+			}})
+	case strings.HasPrefix(err.Error(), unknownField):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownField), `"`)
+		return apperrors.NewValidationError("request body does not match expected schema",
+			[]apperrors.FieldError{{
+				Field:   field,
+				Message: "unknown field",
+			}})
+	}
+	return apperrors.CreateError(apperrors.ErrorDataValidation, "failed to decode request body", err)
+}
+
+func errPayloadTooLarge(maxSize int64) error {
+	return apperrors.NewAppError(apperrors.ErrorDataTooLarge,
+		fmt.Sprintf("request body exceeds %d bytes", maxSize))
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+type testTarget struct {
+	Name   string `json:"name" validate:"required,len:1:8"`
+	Hash   string `json:"hash" validate:"required,hex:64"`
+	Data   string `json:"data,omitempty" validate:"base64"`
+	ID     string `json:"id,omitempty" validate:"uuid"`
+	Format string `json:"format,omitempty" validate:"enum:BINARY|OSTREE"`
+}
+
+type testUpdate struct {
+	Namespace string       `json:"namespace" validate:"required,namespace"`
+	Targets   []testTarget `json:"targets" validate:"required,len:1:2"`
+	Comment   *string      `json:"comment,omitempty" validate:"len:0:10"`
+}
+
+const testHash = "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"
+
+func TestBindJSON(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.POST("/updates", func(c echo.Context) error {
+		var upd testUpdate
+		if err := api.BindJSONWithLimit(c, &upd, 512); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, upd)
+	})
+	post := func(t *testing.T, body string) (int, api.ErrorResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var resp api.ErrorResponse
+		if rec.Code != http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("json.Unmarshal returned error: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("should bind valid body", func(t *testing.T) {
+		body := `{"namespace":"default","targets":[{"name":"fw","hash":"` + testHash +
+			`","data":"aGVsbG8=","id":"4bf92f35-77b3-4da6-a3ce-929d0e0e4736","format":"BINARY"}]}`
+		if code, resp := post(t, body); code != http.StatusOK {
+			t.Errorf("got status %d (%+v), expected %d", code, resp, http.StatusOK)
+		}
+	})
+	t.Run("should return per-field validation errors", func(t *testing.T) {
+		body := `{"namespace":"bad ns","targets":[{"name":"too-long-name","hash":"abc","data":"%%","id":"1","format":"ZIP"}]}`
+		code, resp := post(t, body)
+		if code != http.StatusBadRequest {
+			t.Fatalf("got status %d, expected %d", code, http.StatusBadRequest)
+		}
+		if resp.ErrorCode != apperrors.ErrorDataValidation {
+			t.Errorf("got error code %s, expected %s", resp.ErrorCode, apperrors.ErrorDataValidation)
+		}
+		expected := []string{"namespace", "targets[0].name", "targets[0].hash", "targets[0].data", "targets[0].id", "targets[0].format"}
+		if len(resp.Details) != len(expected) {
+			t.Fatalf("got details %+v, expected fields %v", resp.Details, expected)
+		}
+		for i, field := range expected {
+			if resp.Details[i].Field != field {
+				t.Errorf("got field %s, expected %s", resp.Details[i].Field, field)
+			}
+		}
+	})
+	t.Run("should report missing required fields", func(t *testing.T) {
+		_, resp := post(t, `{"targets":[]}`)
+		if len(resp.Details) != 2 || resp.Details[0].Field != "namespace" || resp.Details[1].Field != "targets" {
+			t.Errorf("got details %+v, expected namespace and targets to be required", resp.Details)
+		}
+	})
+	t.Run("should reject unknown fields", func(t *testing.T) {
+		code, resp := post(t, `{"namespace":"default","admin":true}`)
+		if code != http.StatusBadRequest || len(resp.Details) != 1 || resp.Details[0].Field != "admin" {
+			t.Errorf("got status %d details %+v, expected unknown field admin", code, resp.Details)
+		}
+	})
+	t.Run("should reject malformed body", func(t *testing.T) {
+		for _, body := range []string{``, `{"namespace":`, `{} {}`, `{"targets":"x"}`} {
+			if code, _ := post(t, body); code != http.StatusBadRequest {
+				t.Errorf("%q: got status %d, expected %d", body, code, http.StatusBadRequest)
+			}
+		}
+	})
+	t.Run("should reject oversized body", func(t *testing.T) {
+		body := `{"namespace":"` + strings.Repeat("a", 1024) + `"}`
+		code, resp := post(t, body)
+		if code != http.StatusRequestEntityTooLarge || resp.ErrorCode != apperrors.ErrorDataTooLarge {
+			t.Errorf("got status %d (%s), expected %d", code, resp.ErrorCode, http.StatusRequestEntityTooLarge)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// DefaultConcurrencyInitialLimit is default initial limit of in-flight requests
+	DefaultConcurrencyInitialLimit = 20
+	// DefaultConcurrencyMinLimit is default minimum limit of in-flight requests of adaptive limiter
+	DefaultConcurrencyMinLimit = 4
+	// DefaultConcurrencyMaxLimit is default maximum limit of in-flight requests of adaptive limiter
+	DefaultConcurrencyMaxLimit = 1000
+	// DefaultConcurrencySmoothing is default smoothing factor of adaptive limit changes
+	DefaultConcurrencySmoothing = 0.2
+	// DefaultConcurrencyTolerance is default ratio of latency increase tolerated before limit decreases
+	DefaultConcurrencyTolerance = 1.5
+	// DefaultConcurrencyRetryAfter is default Retry-After of rejected requests
+	DefaultConcurrencyRetryAfter = time.Second
+
+	// concurrencyLongWindow is number of samples of long-term latency average
+	concurrencyLongWindow = 600
+	// concurrencyBackoff is multiplicative decrease of limit on dropped (timed out) request
+	concurrencyBackoff = 0.9
+)
+
+// ConcurrencyConfig is configuration of ConcurrencyLimiter
+type ConcurrencyConfig struct {
+	// InitialLimit is limit of in-flight requests (initial limit of adaptive limiter)
+	InitialLimit int
+	// MinLimit is minimum limit of adaptive limiter
+	MinLimit int
+	// MaxLimit is maximum limit of adaptive limiter
+	MaxLimit int
+	// Adaptive enables gradient based limit adjustment using requests latency
+	Adaptive bool
+	// Smoothing is factor of adaptive limit changes in range (0, 1]
+	Smoothing float64
+	// Tolerance is ratio of latency increase tolerated before limit decreases
+	Tolerance float64
+	// RetryAfter is Retry-After of rejected requests
+	RetryAfter time.Duration
+	// Priority defines requests admitted regardless of limit (health, metrics and admin routes by default)
+	Priority func(echo.Context) bool
+	// Prefix is prometheus namespace of limiter metrics (ex. service name)
+	Prefix string
+	// Skipper defines a function to skip middleware (server-sent events streams by default,
+	// long-lived streams would hold limiter slots and distort latency gradient)
+	Skipper middleware.Skipper
+}
+
+// DefaultConcurrencyConfig returns default configuration of ConcurrencyLimiter
+func DefaultConcurrencyConfig() ConcurrencyConfig {
+	return ConcurrencyConfig{
+		InitialLimit: DefaultConcurrencyInitialLimit,
+		MinLimit:     DefaultConcurrencyMinLimit,
+		MaxLimit:     DefaultConcurrencyMaxLimit,
+		Smoothing:    DefaultConcurrencySmoothing,
+		Tolerance:    DefaultConcurrencyTolerance,
+		RetryAfter:   DefaultConcurrencyRetryAfter,
+		Priority:     isServiceRoute,
+		Skipper:      EventStreamSkipper,
+	}
+}
+
+// ConcurrencyLimiter limits number of in-flight requests,
+// adaptive limiter adjusts limit using gradient of short-term and long-term latency
+// (in the style of Netflix concurrency-limits Gradient2)
+type ConcurrencyLimiter struct {
+	cfg ConcurrencyConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	longRTT  float64
+	samples  int
+
+	rejected prometheus.Counter
+}
+
+// NewConcurrencyLimiter creates new ConcurrencyLimiter
+func NewConcurrencyLimiter(cfg ConcurrencyConfig) *ConcurrencyLimiter {
+	def := DefaultConcurrencyConfig()
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = def.InitialLimit
+	}
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = def.MinLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = def.MaxLimit
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = def.Smoothing
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = def.Tolerance
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = def.RetryAfter
+	}
+	if cfg.Priority == nil {
+		cfg.Priority = def.Priority
+	}
+	if cfg.Skipper == nil {
+		cfg.Skipper = def.Skipper
+	}
+	return &ConcurrencyLimiter{
+		cfg:   cfg,
+		limit: float64(cfg.InitialLimit),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: cfg.Prefix,
+			Subsystem: "http",
+			Name:      "concurrency_rejected_total",
+			Help:      "Total number of requests rejected by concurrency limiter.",
+		}),
+	}
+}
+
+// Limit returns current limit of in-flight requests
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns number of in-flight requests
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Acquire reserves in-flight request slot, it returns false if limit is reached,
+// returned release func should be called on request completion
+// (dropped reports request failed because of overload, ex. timeout)
+func (l *ConcurrencyLimiter) Acquire() (release func(rtt time.Duration, dropped bool), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	inFlight := l.inFlight
+	return func(rtt time.Duration, dropped bool) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight--
+		if l.cfg.Adaptive {
+			l.update(rtt, dropped, inFlight)
+		}
+	}, true
+}
+
+// update adjusts limit using latency sample of request, it should be called under lock
+func (l *ConcurrencyLimiter) update(rtt time.Duration, dropped bool, inFlight int) {
+	if dropped {
+		l.limit = l.clamp(l.limit * concurrencyBackoff)
+		return
+	}
+	sample := rtt.Seconds()
+	if sample <= 0 {
+		return
+	}
+	if l.samples < concurrencyLongWindow {
+		l.samples++
+	}
+	if l.longRTT == 0 {
+		l.longRTT = sample
+	} else {
+		l.longRTT += (sample - l.longRTT) / float64(l.samples)
+	}
+	// do not grow limit if it is not used (application limited)
+	if float64(inFlight) < l.limit/2 {
+		return
+	}
+	// long-term average is too high after latency recovery, decay it faster
+	if l.longRTT/sample > 2 {
+		l.longRTT *= 0.95
+	}
+	gradient := math.Max(0.5, math.Min(1, l.cfg.Tolerance*l.longRTT/sample))
+	queueSize := math.Sqrt(l.limit)
+	next := l.limit*gradient + queueSize
+	l.limit = l.clamp(l.limit*(1-l.cfg.Smoothing) + next*l.cfg.Smoothing)
+}
+
+func (l *ConcurrencyLimiter) clamp(limit float64) float64 {
+	return math.Max(float64(l.cfg.MinLimit), math.Min(float64(l.cfg.MaxLimit), limit))
+}
+
+// Middleware returns echo middleware rejecting requests above limit with 503 status and Retry-After header,
+// priority requests are admitted regardless of limit
+func (l *ConcurrencyLimiter) Middleware() echo.MiddlewareFunc {
+	retryAfter := fmt.Sprintf("%d", durationSeconds(l.cfg.RetryAfter))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if l.cfg.Skipper(c) || l.cfg.Priority(c) {
+				return next(c)
+			}
+			release, ok := l.Acquire()
+			if !ok {
+				l.rejected.Inc()
+				c.Response().Header().Set(HeaderRetryAfter, retryAfter)
+				return apperrors.NewAppError(apperrors.ErrorSvcOverloaded, "server is overloaded")
+			}
+			start := time.Now()
+			defer func() {
+				dropped := errors.Is(err, context.DeadlineExceeded) ||
+					errors.Is(c.Request().Context().Err(), context.DeadlineExceeded) ||
+					c.Response().Status == http.StatusGatewayTimeout
+				release(time.Since(start), dropped)
+			}()
+			return next(c)
+		}
+	}
+}
+
+// ReadinessCheck returns non-critical health check reporting degraded status if limit is reached
+func (l *ConcurrencyLimiter) ReadinessCheck() HealthCheck {
+	return HealthCheck{
+		Name:        "concurrency",
+		NonCritical: true,
+		Check: func(ctx context.Context) HealthEntryStatus {
+			l.mu.Lock()
+			limit, inFlight := int(l.limit), l.inFlight
+			l.mu.Unlock()
+			status := StatusHealthy
+			if inFlight >= limit {
+				status = StatusDegraded
+			}
+			return HealthEntryStatus{
+				Status: status,
+				Data:   fmt.Sprintf("in-flight %d, limit %d", inFlight, limit),
+			}
+		},
+	}
+}
+
+// Collectors returns prometheus collectors of limiter (see Metrics.Register)
+func (l *ConcurrencyLimiter) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: l.cfg.Prefix,
+			Subsystem: "http",
+			Name:      "concurrency_limit",
+			Help:      "Current limit of in-flight requests.",
+		}, func() float64 { return float64(l.Limit()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: l.cfg.Prefix,
+			Subsystem: "http",
+			Name:      "concurrency_in_flight",
+			Help:      "Current number of in-flight requests admitted by concurrency limiter.",
+		}, func() float64 { return float64(l.InFlight()) }),
+		l.rejected,
+	}
+}
+
+// isServiceRoute reports if request is to health, metrics or admin endpoint
+func isServiceRoute(c echo.Context) bool {
+	path := c.Path()
+	if path == "" {
+		path = c.Request().URL.Path
+	}
+	switch path {
+	case LivenessPath, ReadinessPath, StartupPath, MetricsPath, HealthHistoryPath:
+		return true
+	}
+	return strings.HasPrefix(path, ReadinessPath+"/") || strings.HasPrefix(path, AdminPath+"/")
+}
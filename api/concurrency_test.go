@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Run("should reject requests above limit and admit health and event stream requests", func(t *testing.T) {
+		l := api.NewConcurrencyLimiter(api.ConcurrencyConfig{InitialLimit: 1, MinLimit: 1})
+		e := echo.New()
+		e.HTTPErrorHandler = api.NewErrorHandler().Handler
+		e.Use(l.Middleware())
+		started, release := make(chan struct{}), make(chan struct{})
+		e.GET("/slow", func(c echo.Context) error {
+			close(started)
+			<-release
+			return c.NoContent(http.StatusOK)
+		})
+		e.GET("/fast", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+		e.GET(api.LivenessPath, api.HealthzHandler)
+		serve := func(path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			return rec
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			serve("/slow")
+		}()
+		<-started
+		rec := serve("/fast")
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get(api.HeaderRetryAfter) != "1" {
+			t.Errorf("got status %d Retry-After %q, expected %d", rec.Code, rec.Header().Get(api.HeaderRetryAfter), http.StatusServiceUnavailable)
+		}
+		if rec = serve(api.LivenessPath); rec.Code != http.StatusOK {
+			t.Errorf("health request: got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+		req.Header.Set(echo.HeaderAccept, api.MIMETextEventStream)
+		rec = httptest.NewRecorder()
+		if e.ServeHTTP(rec, req); rec.Code != http.StatusOK {
+			t.Errorf("event stream request: got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+		if status := l.ReadinessCheck().Check(context.Background()); status.Status != api.StatusDegraded {
+			t.Errorf("got readiness %s, expected %s", status.Status, api.StatusDegraded)
+		}
+		close(release)
+		<-done
+		if rec = serve("/fast"); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+	})
+	t.Run("adaptive limit should follow latency", func(t *testing.T) {
+		l := api.NewConcurrencyLimiter(api.ConcurrencyConfig{InitialLimit: 10, MinLimit: 2, MaxLimit: 100, Adaptive: true})
+		// run saturated batches of requests with given latency
+		run := func(rtt time.Duration, batches int) {
+			for i := 0; i < batches; i++ {
+				var releases []func(time.Duration, bool)
+				for {
+					release, ok := l.Acquire()
+					if !ok {
+						break
+					}
+					releases = append(releases, release)
+				}
+				for _, release := range releases {
+					release(rtt, false)
+				}
+			}
+		}
+		run(10*time.Millisecond, 10)
+		grown := l.Limit()
+		if grown <= 10 {
+			t.Fatalf("got limit %d, expected limit to grow with stable latency", grown)
+		}
+		run(100*time.Millisecond, 5)
+		if l.Limit() >= grown {
+			t.Errorf("got limit %d, expected limit to decrease below %d with increased latency", l.Limit(), grown)
+		}
+	})
+	t.Run("should expose limit metrics", func(t *testing.T) {
+		l := api.NewConcurrencyLimiter(api.ConcurrencyConfig{InitialLimit: 7})
+		m, err := api.NewMetrics(api.DefaultMetricsConfig())
+		if err != nil {
+			t.Fatalf("NewMetrics returned error: %v", err)
+		}
+		if err = m.Register(l.Collectors()...); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+		e := echo.New()
+		e.GET(api.MetricsPath, api.MetricsHandler(m))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.MetricsPath, nil))
+		if !strings.Contains(rec.Body.String(), "http_concurrency_limit 7") {
+			t.Error("metrics output does not contain current limit")
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/shuvava/go-logging/logger"
 
 	"github.com/shuvava/go-ota-svc-common/data"
+	"github.com/shuvava/go-ota-svc-common/tracing"
 )
 
 const (
@@ -26,8 +27,14 @@ func GetRequestContext(ctx echo.Context) context.Context {
 
 	newCtx := context.
 		WithValue(c, logger.ContextKeyRequestID, rid)
-	return context.
+	newCtx = context.
 		WithValue(newCtx, logger.ContextKeyTenantID, tenantID)
+	return tracing.WithTraceID(newCtx)
+}
+
+// GetRequestLogger returns logger with request context and trace id fields
+func GetRequestLogger(ctx echo.Context, lgr logger.Logger) logger.Logger {
+	return tracing.WithLogger(GetRequestContext(ctx), lgr)
 }
 
 // GetContentType returns value of ContentType header
@@ -57,11 +64,15 @@ func GetNamespace(ctx echo.Context) data.Namespace {
 	return data.NewNamespace(ns)
 }
 
-// GetRequestID returns RequestID from header
+// GetRequestID returns RequestID from header,
+// if header is missing trace id of current span is used as RequestID
 func GetRequestID(ctx echo.Context) string {
 	rid := ctx.Request().Header.Get(echo.HeaderXRequestID)
-	if rid == "" {
-		rid = data.NewCorrelationID().String()
+	if rid != "" {
+		return rid
+	}
+	if cid, ok := tracing.CorrelationID(ctx.Request().Context()); ok {
+		return cid.String()
 	}
-	return rid
+	return data.NewCorrelationID().String()
 }
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// HeaderRange is Range request header
+	HeaderRange = "Range"
+	// HeaderIfRange is If-Range conditional request header
+	HeaderIfRange = "If-Range"
+	// HeaderAcceptRanges is Accept-Ranges response header
+	HeaderAcceptRanges = "Accept-Ranges"
+	// HeaderContentRange is Content-Range response header
+	HeaderContentRange = "Content-Range"
+	// HeaderContentDigest is response header with digest of response content (RFC 9530)
+	HeaderContentDigest = "Content-Digest"
+	// HeaderReprDigest is response header with digest of selected representation (RFC 9530)
+	HeaderReprDigest = "Repr-Digest"
+
+	// MIMEMultipartByteRanges is content type of response with multiple byte ranges
+	MIMEMultipartByteRanges = "multipart/byteranges"
+
+	// maxByteRanges is maximum number of byte ranges served in one response
+	// (Range header with more ranges is ignored)
+	maxByteRanges = 32
+
+	digestAlgorithmSHA256 = "sha-256"
+	rangeUnitBytes        = "bytes"
+)
+
+var (
+	errInvalidRange       = errors.New("invalid range")
+	errUnsatisfiableRange = errors.New("unsatisfiable range")
+)
+
+// BlobSource provides content of blob byte ranges (ex. blob-store client supporting ranged reads)
+type BlobSource interface {
+	// ReadRange returns reader of length bytes of content starting from offset
+	ReadRange(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// Blob is binary content served by ServeBlob
+type Blob struct {
+	// Source provides content of blob
+	Source BlobSource
+	// Size is size of content in bytes
+	Size int64
+	// SHA256 is hex encoded SHA-256 digest of content (used as ETag and Content-Digest/Repr-Digest)
+	SHA256 string
+	// ContentType is media type of content (application/octet-stream if not set)
+	ContentType string
+	// ModTime is last modification time of content (used by Last-Modified and If-Range)
+	ModTime time.Time
+}
+
+// NewSeekerBlob creates Blob of content, its size is determined by seeking to end of content
+func NewSeekerBlob(content io.ReadSeeker, sha256 string) (Blob, error) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Blob{}, apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to get size of content", err)
+	}
+	return Blob{Source: seekerSource{content}, Size: size, SHA256: sha256}, nil
+}
+
+// seekerSource is BlobSource reading io.ReadSeeker (ranges should be read sequentially)
+type seekerSource struct {
+	content io.ReadSeeker
+}
+
+func (s seekerSource) ReadRange(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+	if _, err := s.content.Seek(offset, io.SeekStart); err != nil {
+		return nil, apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to seek content", err)
+	}
+	return io.NopCloser(io.LimitReader(s.content, length)), nil
+}
+
+// ServeBlob sends blob content supporting Range (including multiple ranges sent as multipart/byteranges),
+// If-Range, If-Match and If-None-Match request headers.
+// Response contains ETag and Repr-Digest headers built from SHA-256 digest of blob,
+// Content-Digest header is sent only with full content.
+func ServeBlob(ctx echo.Context, blob Blob) error {
+	req := ctx.Request()
+	h := ctx.Response().Header()
+	var etag, digest string
+	if blob.SHA256 != "" {
+		sum, err := hex.DecodeString(blob.SHA256)
+		if err != nil {
+			return apperrors.CreateError(apperrors.ErrorDataSerialization, "invalid SHA-256 digest of blob", err)
+		}
+		etag = strconv.Quote(strings.ToLower(blob.SHA256))
+		digest = fmt.Sprintf("%s=:%s:", digestAlgorithmSHA256, base64.StdEncoding.EncodeToString(sum))
+	}
+	if ifMatch := req.Header.Get(HeaderIfMatch); ifMatch != "" && !etagMatch(ifMatch, etag, false) {
+		return apperrors.NewAppError(apperrors.ErrorSvcPreconditionFailed,
+			"resource was modified (If-Match precondition failed)")
+	}
+	if etag != "" && NotModified(ctx, etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	// entity headers are sent only if content can be read (error response is sent otherwise)
+	h.Del(HeaderETag)
+	entity := http.Header{}
+	if etag != "" {
+		entity.Set(HeaderETag, etag)
+	}
+	if !blob.ModTime.IsZero() {
+		entity.Set(echo.HeaderLastModified, blob.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if digest != "" {
+		entity.Set(HeaderReprDigest, digest)
+	}
+	if blob.ContentType == "" {
+		blob.ContentType = echo.MIMEOctetStream
+	}
+	entity.Set(HeaderAcceptRanges, rangeUnitBytes)
+
+	var ranges []byteRange
+	if rangeHeader := req.Header.Get(HeaderRange); rangeHeader != "" && ifRangeMatch(ctx, etag, blob.ModTime) {
+		var err error
+		ranges, err = parseRange(rangeHeader, blob.Size)
+		if errors.Is(err, errUnsatisfiableRange) {
+			h.Set(HeaderContentRange, fmt.Sprintf("%s */%d", rangeUnitBytes, blob.Size))
+			return apperrors.NewAppError(apperrors.ErrorDataRangeNotSatisfiable,
+				fmt.Sprintf("requested range is outside of content of %d bytes", blob.Size))
+		}
+		// invalid Range header is ignored and full content is sent
+	}
+
+	switch len(ranges) {
+	case 0:
+		if digest != "" {
+			entity.Set(HeaderContentDigest, digest)
+		}
+		entity.Set(echo.HeaderContentType, blob.ContentType)
+		return sendBlobRange(ctx, blob, http.StatusOK, byteRange{length: blob.Size}, entity)
+	case 1:
+		entity.Set(HeaderContentRange, ranges[0].contentRange(blob.Size))
+		entity.Set(echo.HeaderContentType, blob.ContentType)
+		return sendBlobRange(ctx, blob, http.StatusPartialContent, ranges[0], entity)
+	}
+	return sendBlobRanges(ctx, blob, ranges, entity)
+}
+
+// sendBlobRange sends single byte range of blob
+func sendBlobRange(ctx echo.Context, blob Blob, code int, r byteRange, entity http.Header) error {
+	res := ctx.Response()
+	entity.Set(echo.HeaderContentLength, strconv.FormatInt(r.length, 10))
+	if ctx.Request().Method == http.MethodHead {
+		writeEntityHeader(res, code, entity)
+		return nil
+	}
+	rc, err := blob.Source.ReadRange(GetRequestContext(ctx), r.start, r.length)
+	if err != nil {
+		return err
+	}
+	writeEntityHeader(res, code, entity)
+	return copyBlobRange(res, rc, r)
+}
+
+// sendBlobRanges sends multiple byte ranges of blob as multipart/byteranges
+func sendBlobRanges(ctx echo.Context, blob Blob, ranges []byteRange, entity http.Header) error {
+	res := ctx.Response()
+	mw := multipart.NewWriter(res)
+	size, err := multipartRangesSize(mw.Boundary(), blob, ranges)
+	if err != nil {
+		return err
+	}
+	entity.Set(echo.HeaderContentType, fmt.Sprintf("%s; boundary=%s", MIMEMultipartByteRanges, mw.Boundary()))
+	entity.Set(echo.HeaderContentLength, strconv.FormatInt(size, 10))
+	if ctx.Request().Method == http.MethodHead {
+		writeEntityHeader(res, http.StatusPartialContent, entity)
+		return nil
+	}
+	reqCtx := GetRequestContext(ctx)
+	// first range is opened before headers are sent to report source failure by error response
+	rc, err := blob.Source.ReadRange(reqCtx, ranges[0].start, ranges[0].length)
+	if err != nil {
+		return err
+	}
+	writeEntityHeader(res, http.StatusPartialContent, entity)
+	for i, r := range ranges {
+		if i > 0 {
+			if rc, err = blob.Source.ReadRange(reqCtx, r.start, r.length); err != nil {
+				return err
+			}
+		}
+		part, err := mw.CreatePart(r.partHeader(blob))
+		if err != nil {
+			_ = rc.Close()
+			return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to send content", err)
+		}
+		if err = copyBlobRange(part, rc, r); err != nil {
+			return err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to send content", err)
+	}
+	return nil
+}
+
+// writeEntityHeader adds entity headers to response and sends status code
+func writeEntityHeader(res *echo.Response, code int, entity http.Header) {
+	for k, v := range entity {
+		res.Header()[k] = v
+	}
+	res.WriteHeader(code)
+}
+
+// copyBlobRange copies content of byte range to w and closes reader
+func copyBlobRange(w io.Writer, rc io.ReadCloser, r byteRange) error {
+	defer rc.Close()
+	if _, err := io.CopyN(w, rc, r.length); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to send content", err)
+	}
+	return nil
+}
+
+// multipartRangesSize returns size of multipart/byteranges response body
+func multipartRangesSize(boundary string, blob Blob, ranges []byteRange) (int64, error) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return 0, apperrors.CreateError(apperrors.ErrorGeneric, "invalid multipart boundary", err)
+	}
+	for _, r := range ranges {
+		_, _ = mw.CreatePart(r.partHeader(blob))
+		w += countingWriter(r.length)
+	}
+	_ = mw.Close()
+	return int64(w), nil
+}
+
+// countingWriter counts written bytes
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// byteRange is range of content bytes
+type byteRange struct {
+	start, length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("%s %d-%d/%d", rangeUnitBytes, r.start, r.start+r.length-1, size)
+}
+
+func (r byteRange) partHeader(blob Blob) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		echo.HeaderContentType: {blob.ContentType},
+		HeaderContentRange:     {r.contentRange(blob.Size)},
+	}
+}
+
+// parseRange parses Range header (ex. "bytes=0-499,-500"),
+// it returns errInvalidRange if header should be ignored
+// and errUnsatisfiableRange if none of ranges overlaps content
+func parseRange(header string, size int64) ([]byteRange, error) {
+	unit, specs, found := strings.Cut(header, "=")
+	if !found || strings.TrimSpace(unit) != rangeUnitBytes {
+		return nil, errInvalidRange
+	}
+	var (
+		ranges    []byteRange
+		total     int64
+		noOverlap bool
+	)
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		first, last, found := strings.Cut(spec, "-")
+		if !found {
+			return nil, errInvalidRange
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		var r byteRange
+		if first == "" {
+			// suffix range (last N bytes)
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			if n == 0 || size == 0 {
+				noOverlap = true
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errInvalidRange
+			}
+			end := size - 1
+			if last != "" {
+				e, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || e < start {
+					return nil, errInvalidRange
+				}
+				if e < end {
+					end = e
+				}
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			r = byteRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, r)
+		total += r.length
+	}
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, errUnsatisfiableRange
+		}
+		return nil, errInvalidRange
+	}
+	// too many or overlapping ranges are ignored (protection from amplification)
+	if len(ranges) > maxByteRanges || total > size {
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
+
+// ifRangeMatch reports if If-Range header (if any) matches strong ETag or modification time of content
+func ifRangeMatch(ctx echo.Context, etag string, modTime time.Time) bool {
+	ifRange := strings.TrimSpace(ctx.Request().Header.Get(HeaderIfRange))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, etagWeakPrefix) {
+		return etagMatch(ifRange, etag, false)
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(t)
+}
@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+// failingSource is blob source failing to read content (ex. missing object of blob store)
+type failingSource struct{}
+
+func (failingSource) ReadRange(context.Context, int64, int64) (io.ReadCloser, error) {
+	return nil, apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "blob not found")
+}
+
+func TestServeBlob(t *testing.T) {
+	content := "0123456789"
+	sha := data.Digest(content)
+	etag := strconv.Quote(sha)
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.GET("/blob", func(c echo.Context) error {
+		blob, err := api.NewSeekerBlob(strings.NewReader(content), sha)
+		if err != nil {
+			return err
+		}
+		return api.ServeBlob(c, blob)
+	})
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/blob", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should send full content with digests", func(t *testing.T) {
+		rec := serve(nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != content {
+			t.Fatalf("got status %d body %q", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get(api.HeaderETag) != etag || rec.Header().Get(api.HeaderAcceptRanges) != "bytes" {
+			t.Errorf("got headers %v", rec.Header())
+		}
+		digest := rec.Header().Get(api.HeaderReprDigest)
+		if !strings.HasPrefix(digest, "sha-256=:") || rec.Header().Get(api.HeaderContentDigest) != digest {
+			t.Errorf("got digest headers %v", rec.Header())
+		}
+	})
+	t.Run("should send single range", func(t *testing.T) {
+		for rng, expected := range map[string]string{"bytes=2-5": "2345", "bytes=-3": "789", "bytes=7-": "789", "bytes=8-100": "89"} {
+			rec := serve(map[string]string{api.HeaderRange: rng})
+			if rec.Code != http.StatusPartialContent || rec.Body.String() != expected {
+				t.Errorf("%s: got status %d body %q, expected %q", rng, rec.Code, rec.Body.String(), expected)
+			}
+			if rec.Header().Get(api.HeaderContentDigest) != "" {
+				t.Errorf("%s: got Content-Digest of partial content", rng)
+			}
+		}
+		rec := serve(map[string]string{api.HeaderRange: "bytes=2-5"})
+		if rec.Header().Get(api.HeaderContentRange) != "bytes 2-5/10" {
+			t.Errorf("got Content-Range %q", rec.Header().Get(api.HeaderContentRange))
+		}
+	})
+	t.Run("should send multiple ranges as multipart/byteranges", func(t *testing.T) {
+		rec := serve(map[string]string{api.HeaderRange: "bytes=0-1, 8-"})
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("got status %d, expected %d", rec.Code, http.StatusPartialContent)
+		}
+		if rec.Header().Get(echo.HeaderContentLength) != strconv.Itoa(rec.Body.Len()) {
+			t.Errorf("got Content-Length %s, expected %d", rec.Header().Get(echo.HeaderContentLength), rec.Body.Len())
+		}
+		mediaType, params, _ := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentType))
+		if mediaType != api.MIMEMultipartByteRanges {
+			t.Fatalf("got content type %q", mediaType)
+		}
+		mr := multipart.NewReader(rec.Body, params["boundary"])
+		var parts []string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			body, _ := io.ReadAll(p)
+			parts = append(parts, p.Header.Get(api.HeaderContentRange)+"="+string(body))
+		}
+		if strings.Join(parts, ";") != "bytes 0-1/10=01;bytes 8-9/10=89" {
+			t.Errorf("got parts %v", parts)
+		}
+	})
+	t.Run("should reject unsatisfiable range", func(t *testing.T) {
+		rec := serve(map[string]string{api.HeaderRange: "bytes=20-"})
+		if rec.Code != http.StatusRequestedRangeNotSatisfiable || rec.Header().Get(api.HeaderContentRange) != "bytes */10" {
+			t.Errorf("got status %d headers %v", rec.Code, rec.Header())
+		}
+	})
+	t.Run("should ignore invalid range and range of changed content", func(t *testing.T) {
+		if rec := serve(map[string]string{api.HeaderRange: "bytes=5-2"}); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+		rec := serve(map[string]string{api.HeaderRange: "bytes=0-1", api.HeaderIfRange: `"other"`})
+		if rec.Code != http.StatusOK || rec.Body.String() != content {
+			t.Errorf("got status %d, expected full content", rec.Code)
+		}
+		if rec = serve(map[string]string{api.HeaderRange: "bytes=0-1", api.HeaderIfRange: etag}); rec.Code != http.StatusPartialContent {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusPartialContent)
+		}
+	})
+	t.Run("should handle conditional requests", func(t *testing.T) {
+		if rec := serve(map[string]string{api.HeaderIfNoneMatch: etag}); rec.Code != http.StatusNotModified {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusNotModified)
+		}
+		if rec := serve(map[string]string{api.HeaderIfMatch: `"other"`}); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusPreconditionFailed)
+		}
+	})
+	t.Run("should send error response without entity headers if content cannot be read", func(t *testing.T) {
+		e.GET("/missing", func(c echo.Context) error {
+			return api.ServeBlob(c, api.Blob{Source: failingSource{}, Size: int64(len(content)), SHA256: sha})
+		})
+		for _, rng := range []string{"", "bytes=0-1", "bytes=0-1,4-5"} {
+			req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			if rng != "" {
+				req.Header.Set(api.HeaderRange, rng)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%q: got status %d, expected %d", rng, rec.Code, http.StatusNotFound)
+			}
+			for _, name := range []string{echo.HeaderContentLength, api.HeaderContentDigest, api.HeaderETag, api.HeaderContentRange} {
+				if v := rec.Header().Get(name); v != "" {
+					t.Errorf("%q: got header %s: %s", rng, name, v)
+				}
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, echo.MIMEApplicationJSON) {
+				t.Errorf("%q: got content type %q", rng, ct)
+			}
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// HeaderIfMatch is If-Match conditional request header
+	HeaderIfMatch = "If-Match"
+	// HeaderIfNoneMatch is If-None-Match conditional request header
+	HeaderIfNoneMatch = "If-None-Match"
+	// HeaderETag is ETag response header
+	HeaderETag = "ETag"
+
+	etagAny        = "*"
+	etagWeakPrefix = "W/"
+)
+
+// ETag returns strong ETag of response representation
+func ETag(content []byte) string {
+	return strconv.Quote(data.ByteDigest(content))
+}
+
+// VersionETag returns strong ETag of document version
+func VersionETag(version int64) string {
+	return strconv.Quote(data.Digest(strconv.FormatInt(version, 10)))
+}
+
+// CheckPreconditions evaluates If-Match and If-None-Match headers of state changing request
+// against current ETag of resource (empty if resource does not exist),
+// it returns AppError with ErrorSvcPreconditionFailed code if precondition is false
+func CheckPreconditions(ctx echo.Context, etag string) error {
+	header := ctx.Request().Header
+	if ifMatch := header.Get(HeaderIfMatch); ifMatch != "" && !etagMatch(ifMatch, etag, false) {
+		return apperrors.NewAppError(apperrors.ErrorSvcPreconditionFailed,
+			"resource was modified (If-Match precondition failed)")
+	}
+	if ifNoneMatch := header.Get(HeaderIfNoneMatch); ifNoneMatch != "" && etagMatch(ifNoneMatch, etag, true) {
+		return apperrors.NewAppError(apperrors.ErrorSvcPreconditionFailed,
+			"resource already exists (If-None-Match precondition failed)")
+	}
+	return nil
+}
+
+// IfMatchVersion checks If-Match header against current document version,
+// it returns version which should be passed to repository update as expected one
+// (see mongo.Db.UpdateOneVersioned)
+func IfMatchVersion(ctx echo.Context, version int64) (int64, error) {
+	if err := CheckPreconditions(ctx, VersionETag(version)); err != nil {
+		return 0, err
+	}
+	ctx.Response().Header().Set(HeaderETag, VersionETag(version))
+	return version, nil
+}
+
+// NotModified sets ETag response header and reports if If-None-Match header of GET/HEAD request matches it
+func NotModified(ctx echo.Context, etag string) bool {
+	ctx.Response().Header().Set(HeaderETag, etag)
+	req := ctx.Request()
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	ifNoneMatch := req.Header.Get(HeaderIfNoneMatch)
+	return ifNoneMatch != "" && etagMatch(ifNoneMatch, etag, true)
+}
+
+// JSONWithETag sends JSON response with strong ETag computed over representation,
+// 304 Not Modified is sent if If-None-Match header matches
+func JSONWithETag(ctx echo.Context, code int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return apperrors.CreateError(apperrors.ErrorDataSerialization, "failed to serialize response", err)
+	}
+	if NotModified(ctx, ETag(body)) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	return ctx.JSONBlob(code, body)
+}
+
+// JSONWithVersion sends JSON response with strong ETag of document version,
+// 304 Not Modified is sent if If-None-Match header matches
+func JSONWithVersion(ctx echo.Context, code int, version int64, v interface{}) error {
+	if NotModified(ctx, VersionETag(version)) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	return ctx.JSON(code, v)
+}
+
+// etagMatch reports if comma separated list of entity tags matches etag,
+// weak comparison ignores W/ prefix, strong comparison never matches weak tags
+func etagMatch(header string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == etagAny {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, etagWeakPrefix)
+	} else if strings.HasPrefix(etag, etagWeakPrefix) {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, etagWeakPrefix)
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestETag(t *testing.T) {
+	type campaign struct {
+		Name    string `json:"name"`
+		Version int64  `json:"version"`
+	}
+	current := campaign{Name: "update", Version: 3}
+	var expected int64
+
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.GET("/campaigns/1", func(c echo.Context) error {
+		return api.JSONWithETag(c, http.StatusOK, current)
+	})
+	e.GET("/campaigns/1/version", func(c echo.Context) error {
+		return api.JSONWithVersion(c, http.StatusOK, current.Version, current)
+	})
+	e.PUT("/campaigns/1", func(c echo.Context) error {
+		version, err := api.IfMatchVersion(c, current.Version)
+		if err != nil {
+			return err
+		}
+		expected = version
+		return c.NoContent(http.StatusNoContent)
+	})
+	request := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should return strong ETag of representation", func(t *testing.T) {
+		rec := request(http.MethodGet, "/campaigns/1", nil)
+		etag := rec.Header().Get(api.HeaderETag)
+		if rec.Code != http.StatusOK || etag != api.ETag(rec.Body.Bytes()) {
+			t.Fatalf("got status %d ETag %s", rec.Code, etag)
+		}
+		rec = request(http.MethodGet, "/campaigns/1", map[string]string{api.HeaderIfNoneMatch: `"other", W/` + etag})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusNotModified)
+		}
+	})
+	t.Run("should return ETag of document version", func(t *testing.T) {
+		rec := request(http.MethodGet, "/campaigns/1/version", map[string]string{api.HeaderIfNoneMatch: api.VersionETag(3)})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusNotModified)
+		}
+	})
+	t.Run("should pass expected version if If-Match matches", func(t *testing.T) {
+		expected = 0
+		rec := request(http.MethodPut, "/campaigns/1", map[string]string{api.HeaderIfMatch: api.VersionETag(3)})
+		if rec.Code != http.StatusNoContent || expected != 3 {
+			t.Errorf("got status %d version %d, expected %d version 3", rec.Code, expected, http.StatusNoContent)
+		}
+	})
+	t.Run("should respond with precondition failed if If-Match does not match", func(t *testing.T) {
+		for _, ifMatch := range []string{api.VersionETag(2), "W/" + api.VersionETag(3)} {
+			rec := request(http.MethodPut, "/campaigns/1", map[string]string{api.HeaderIfMatch: ifMatch})
+			if rec.Code != http.StatusPreconditionFailed {
+				t.Errorf("%s: got status %d, expected %d", ifMatch, rec.Code, http.StatusPreconditionFailed)
+			}
+			if !strings.Contains(rec.Body.String(), apperrors.ErrorSvcPreconditionFailed) {
+				t.Errorf("got body %s, expected error code %s", rec.Body.String(), apperrors.ErrorSvcPreconditionFailed)
+			}
+		}
+	})
+	t.Run("should respond with precondition failed if resource exists and If-None-Match is *", func(t *testing.T) {
+		rec := request(http.MethodPut, "/campaigns/1", map[string]string{api.HeaderIfNoneMatch: "*"})
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusPreconditionFailed)
+		}
+	})
+}
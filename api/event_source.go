@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// DefaultEventHistorySize is default number of events kept by EventSource for subscription resume
+	DefaultEventHistorySize = 1000
+	// DefaultEventBufferSize is default size of subscription buffer
+	DefaultEventBufferSize = 64
+)
+
+var (
+	// ErrSlowConsumer is error of subscription closed because its buffer was full
+	ErrSlowConsumer = errors.New("subscription buffer is full (slow consumer)")
+	// ErrEventSourceClosed is error of subscription closed because event source was closed
+	ErrEventSourceClosed = errors.New("event source is closed")
+)
+
+// Event is event published to namespace subscribers
+type Event[T any] struct {
+	// ID is sequence number of event in EventSource
+	ID uint64
+	// Type is type of event (ex. "campaign.progress")
+	Type string
+	// Namespace is OTA namespace of event
+	Namespace data.Namespace
+	// Data is payload of event
+	Data T
+	// Time is time of event publishing
+	Time time.Time
+}
+
+// EventSource is in-process publisher of namespace scoped events,
+// it keeps recent events to resume subscriptions after reconnect
+type EventSource[T any] struct {
+	mu          sync.Mutex
+	seq         uint64
+	history     []Event[T]
+	historySize int
+	subs        map[*Subscription[T]]struct{}
+	closed      bool
+}
+
+// Subscription is subscription to events of namespace,
+// events are delivered by channel C which is closed when subscription ends (see Err)
+type Subscription[T any] struct {
+	// C delivers events of subscription
+	C <-chan Event[T]
+
+	source *EventSource[T]
+	ns     data.Namespace
+	ch     chan Event[T]
+	err    error
+}
+
+// NewEventSource creates EventSource keeping historySize recent events (DefaultEventHistorySize if 0)
+func NewEventSource[T any](historySize int) *EventSource[T] {
+	if historySize <= 0 {
+		historySize = DefaultEventHistorySize
+	}
+	return &EventSource[T]{
+		historySize: historySize,
+		subs:        make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Publish sends event to subscribers of namespace,
+// subscriptions with full buffer are closed with ErrSlowConsumer error
+func (s *EventSource[T]) Publish(ns data.Namespace, eventType string, payload T) Event[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	ev := Event[T]{ID: s.seq, Type: eventType, Namespace: ns, Data: payload, Time: time.Now().UTC()}
+	if s.closed {
+		return ev
+	}
+	if len(s.history) == s.historySize {
+		s.history = s.history[1:]
+	}
+	s.history = append(s.history, ev)
+	for sub := range s.subs {
+		if sub.ns != ns {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			s.unsubscribe(sub, ErrSlowConsumer)
+		}
+	}
+	return ev
+}
+
+// Subscribe creates subscription to events of namespace with buffer of bufferSize events
+// (DefaultEventBufferSize if 0), kept events published after lastID are delivered first
+func (s *EventSource[T]) Subscribe(ns data.Namespace, lastID uint64, bufferSize int) *Subscription[T] {
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var replay []Event[T]
+	if lastID > 0 {
+		for _, ev := range s.history {
+			if ev.ID > lastID && ev.Namespace == ns {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	ch := make(chan Event[T], bufferSize+len(replay))
+	for _, ev := range replay {
+		ch <- ev
+	}
+	sub := &Subscription[T]{C: ch, source: s, ns: ns, ch: ch}
+	if s.closed {
+		sub.err = ErrEventSourceClosed
+		close(ch)
+		return sub
+	}
+	s.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close closes all subscriptions, events published after close are not delivered
+func (s *EventSource[T]) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subs {
+		s.unsubscribe(sub, ErrEventSourceClosed)
+	}
+}
+
+// Subscribers returns number of active subscriptions
+func (s *EventSource[T]) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+// unsubscribe closes subscription, it should be called under lock
+func (s *EventSource[T]) unsubscribe(sub *Subscription[T], err error) {
+	if _, found := s.subs[sub]; !found {
+		return
+	}
+	delete(s.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+// Close ends subscription
+func (sub *Subscription[T]) Close() {
+	sub.source.mu.Lock()
+	defer sub.source.mu.Unlock()
+	sub.source.unsubscribe(sub, nil)
+}
+
+// Err returns reason of subscription end (nil if subscription was closed by subscriber or is active)
+func (sub *Subscription[T]) Err() error {
+	sub.source.mu.Lock()
+	defer sub.source.mu.Unlock()
+	return sub.err
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// QueryFilter is query parameter name of filter expression
+//
+// filter expression grammar:
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expr ")" | comparison
+//	comparison = field op value | field "in" "(" value { "," value } ")"
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "contains" | "startswith"
+//	value      = "quoted string" | number | date | true | false | null
+//
+// ex. hardwareId eq "rpi4" and (createdAt gt 2024-01-01 or not status in ("FAILED", "CANCELED"))
+const QueryFilter = "filter"
+
+const (
+	maxFilterLength = 2048
+	maxFilterDepth  = 16
+	maxFilterValues = 100
+)
+
+// FilterOperator is comparison operator of filter expression
+type FilterOperator string
+
+const (
+	// FilterEq is equal operator
+	FilterEq FilterOperator = "eq"
+	// FilterNe is not equal operator
+	FilterNe FilterOperator = "ne"
+	// FilterGt is greater than operator
+	FilterGt FilterOperator = "gt"
+	// FilterGe is greater or equal operator
+	FilterGe FilterOperator = "ge"
+	// FilterLt is less than operator
+	FilterLt FilterOperator = "lt"
+	// FilterLe is less or equal operator
+	FilterLe FilterOperator = "le"
+	// FilterIn is operator checking if value is in the list
+	FilterIn FilterOperator = "in"
+	// FilterContains is substring operator (string fields only)
+	FilterContains FilterOperator = "contains"
+	// FilterStartsWith is prefix operator (string fields only)
+	FilterStartsWith FilterOperator = "startswith"
+)
+
+// FilterValueKind is kind of filter expression literal
+type FilterValueKind int
+
+const (
+	// FilterValueString is quoted string literal
+	FilterValueString FilterValueKind = iota
+	// FilterValueWord is unquoted literal (number or date)
+	FilterValueWord
+	// FilterValueBool is true or false literal
+	FilterValueBool
+	// FilterValueNull is null literal
+	FilterValueNull
+)
+
+type (
+	// FilterNode is a node of filter expression AST
+	FilterNode interface {
+		filterNode()
+	}
+
+	// FilterAnd is logical conjunction of nodes
+	FilterAnd struct {
+		Nodes []FilterNode
+	}
+
+	// FilterOr is logical disjunction of nodes
+	FilterOr struct {
+		Nodes []FilterNode
+	}
+
+	// FilterNot is logical negation of node
+	FilterNot struct {
+		Node FilterNode
+	}
+
+	// FilterComparison compares field with values (more than one value only for FilterIn)
+	FilterComparison struct {
+		Field  string
+		Op     FilterOperator
+		Values []FilterValue
+	}
+
+	// FilterValue is filter expression literal
+	FilterValue struct {
+		Kind FilterValueKind
+		Raw  string
+	}
+)
+
+func (FilterAnd) filterNode()        {}
+func (FilterOr) filterNode()         {}
+func (FilterNot) filterNode()        {}
+func (FilterComparison) filterNode() {}
+
+// ParseFilter parses filter expression to AST
+func ParseFilter(expr string) (FilterNode, error) {
+	if len(expr) > maxFilterLength {
+		return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("filter expression exceeds %d characters", maxFilterLength))
+	}
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := filterParser{tokens: tokens}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexFilter splits filter expression to tokens
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, text: ",", pos: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, filterError(i, "unterminated string")
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, filterError(i, "invalid string literal")
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: s, pos: i})
+			i = end + 1
+		case isFilterWordChar(rune(c)):
+			end := i
+			for end < len(expr) && isFilterWordChar(rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: expr[i:end], pos: i})
+			i = end
+		default:
+			return nil, filterError(i, fmt.Sprintf("unexpected character %q", c))
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, text: "end of expression", pos: len(expr)}), nil
+}
+
+// isFilterWordChar reports if c is allowed in field names, numbers and dates
+func isFilterWordChar(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) ||
+		c == '_' || c == '.' || c == '-' || c == '+' || c == ':')
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports if next token is keyword kw and consumes it
+func (p *filterParser) keyword(kw string) bool {
+	tok := p.peek()
+	if tok.kind == tokenWord && strings.EqualFold(tok.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr(depth int) (FilterNode, error) {
+	node, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []FilterNode{node}
+	for p.keyword("or") {
+		if node, err = p.parseAnd(depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return FilterOr{Nodes: nodes}, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (FilterNode, error) {
+	node, err := p.parseFactor(depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []FilterNode{node}
+	for p.keyword("and") {
+		if node, err = p.parseFactor(depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return FilterAnd{Nodes: nodes}, nil
+}
+
+func (p *filterParser) parseFactor(depth int) (FilterNode, error) {
+	tok := p.peek()
+	if depth >= maxFilterDepth {
+		return nil, p.errorf(tok, "expression is nested too deep")
+	}
+	if p.keyword("not") {
+		node, err := p.parseFactor(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{Node: node}, nil
+	}
+	if tok.kind == tokenLParen {
+		p.next()
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if tok = p.next(); tok.kind != tokenRParen {
+			return nil, p.errorf(tok, "expected \")\", got %q", tok.text)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (FilterNode, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, p.errorf(field, "expected field name, got %q", field.text)
+	}
+	opTok := p.next()
+	if opTok.kind != tokenWord {
+		return nil, p.errorf(opTok, "expected operator, got %q", opTok.text)
+	}
+	cmp := FilterComparison{Field: field.text, Op: FilterOperator(strings.ToLower(opTok.text))}
+	switch cmp.Op {
+	case FilterEq, FilterNe, FilterGt, FilterGe, FilterLt, FilterLe, FilterContains, FilterStartsWith:
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		cmp.Values = []FilterValue{val}
+	case FilterIn:
+		if tok := p.next(); tok.kind != tokenLParen {
+			return nil, p.errorf(tok, "expected \"(\", got %q", tok.text)
+		}
+		for {
+			val, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			cmp.Values = append(cmp.Values, val)
+			if len(cmp.Values) > maxFilterValues {
+				return nil, p.errorf(opTok, "too many values (maximum %d)", maxFilterValues)
+			}
+			tok := p.next()
+			if tok.kind == tokenRParen {
+				break
+			}
+			if tok.kind != tokenComma {
+				return nil, p.errorf(tok, "expected \",\" or \")\", got %q", tok.text)
+			}
+		}
+	default:
+		return nil, p.errorf(opTok, "unknown operator %q", opTok.text)
+	}
+	return cmp, nil
+}
+
+func (p *filterParser) parseValue() (FilterValue, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return FilterValue{Kind: FilterValueString, Raw: tok.text}, nil
+	case tokenWord:
+		switch strings.ToLower(tok.text) {
+		case "true", "false":
+			return FilterValue{Kind: FilterValueBool, Raw: strings.ToLower(tok.text)}, nil
+		case "null":
+			return FilterValue{Kind: FilterValueNull, Raw: "null"}, nil
+		}
+		return FilterValue{Kind: FilterValueWord, Raw: tok.text}, nil
+	}
+	return FilterValue{}, p.errorf(tok, "expected value, got %q", tok.text)
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return filterError(tok.pos, fmt.Sprintf(format, args...))
+}
+
+func filterError(pos int, msg string) error {
+	return apperrors.NewAppError(apperrors.ErrorDataValidation,
+		fmt.Sprintf("invalid filter expression at position %d: %s", pos, msg))
+}
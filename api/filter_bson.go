@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// FilterType is type of filterable field
+type FilterType int
+
+const (
+	// FilterString is string field
+	FilterString FilterType = iota
+	// FilterInt is integer field
+	FilterInt
+	// FilterFloat is floating point number field
+	FilterFloat
+	// FilterBool is boolean field
+	FilterBool
+	// FilterTime is date/time field (values in RFC3339 or 2006-01-02 format)
+	FilterTime
+)
+
+const filterDateLayout = "2006-01-02"
+
+// FilterField is a field allowed in filter expression
+type FilterField struct {
+	// Type of field values
+	Type FilterType
+	// Field is database field name (field name of expression is used if empty)
+	Field string
+}
+
+// FilterSchema is whitelist of fields allowed in filter expression of resource
+type FilterSchema map[string]FilterField
+
+var filterOperators = map[FilterOperator]string{
+	FilterEq: "$eq",
+	FilterNe: "$ne",
+	FilterGt: "$gt",
+	FilterGe: "$gte",
+	FilterLt: "$lt",
+	FilterLe: "$lte",
+	FilterIn: "$in",
+}
+
+// ParseFilterQuery parses filter query parameter of request and compiles it to mongo filter
+// (empty filter is returned if parameter is missing)
+func ParseFilterQuery(ctx echo.Context, schema FilterSchema) (bson.D, error) {
+	expr := ctx.QueryParam(QueryFilter)
+	if expr == "" {
+		return bson.D{}, nil
+	}
+	node, err := ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Compile(node)
+}
+
+// Compile checks filter expression AST against schema and converts it to mongo filter
+func (s FilterSchema) Compile(node FilterNode) (bson.D, error) {
+	switch n := node.(type) {
+	case FilterAnd:
+		return s.compileLogical("$and", n.Nodes)
+	case FilterOr:
+		return s.compileLogical("$or", n.Nodes)
+	case FilterNot:
+		return s.compileLogical("$nor", []FilterNode{n.Node})
+	case FilterComparison:
+		return s.compileComparison(n)
+	}
+	return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+		fmt.Sprintf("unsupported filter node %T", node))
+}
+
+func (s FilterSchema) compileLogical(op string, nodes []FilterNode) (bson.D, error) {
+	items := bson.A{}
+	for _, node := range nodes {
+		item, err := s.Compile(node)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return bson.D{{Key: op, Value: items}}, nil
+}
+
+func (s FilterSchema) compileComparison(cmp FilterComparison) (bson.D, error) {
+	field, found := s[cmp.Field]
+	if !found {
+		return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("filtering by field %q is not allowed", cmp.Field))
+	}
+	name := field.Field
+	if name == "" {
+		name = cmp.Field
+	}
+
+	values := make(bson.A, 0, len(cmp.Values))
+	for _, val := range cmp.Values {
+		v, err := field.value(cmp, val)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	switch cmp.Op {
+	case FilterContains, FilterStartsWith:
+		if field.Type != FilterString {
+			return nil, filterTypeError(cmp, "operator is supported only for string fields")
+		}
+		pattern := regexp.QuoteMeta(values[0].(string))
+		if cmp.Op == FilterStartsWith {
+			pattern = "^" + pattern
+		}
+		return bson.D{{Key: name, Value: primitive.Regex{Pattern: pattern}}}, nil
+	case FilterIn:
+		return bson.D{{Key: name, Value: bson.D{{Key: "$in", Value: values}}}}, nil
+	}
+	op, found := filterOperators[cmp.Op]
+	if !found {
+		return nil, filterTypeError(cmp, "unknown operator")
+	}
+	return bson.D{{Key: name, Value: bson.D{{Key: op, Value: values[0]}}}}, nil
+}
+
+// value converts filter literal to field type
+func (f FilterField) value(cmp FilterComparison, val FilterValue) (interface{}, error) {
+	if val.Kind == FilterValueNull {
+		if cmp.Op != FilterEq && cmp.Op != FilterNe && cmp.Op != FilterIn {
+			return nil, filterTypeError(cmp, "null is supported only by eq, ne and in operators")
+		}
+		return nil, nil
+	}
+	switch f.Type {
+	case FilterString:
+		if val.Kind == FilterValueString {
+			return val.Raw, nil
+		}
+		return nil, filterTypeError(cmp, "value should be quoted string")
+	case FilterInt:
+		if val.Kind == FilterValueWord {
+			if n, err := strconv.ParseInt(val.Raw, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, filterTypeError(cmp, "value should be integer")
+	case FilterFloat:
+		if val.Kind == FilterValueWord {
+			if n, err := strconv.ParseFloat(val.Raw, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, filterTypeError(cmp, "value should be number")
+	case FilterBool:
+		if val.Kind == FilterValueBool {
+			return val.Raw == "true", nil
+		}
+		return nil, filterTypeError(cmp, "value should be true or false")
+	case FilterTime:
+		if val.Kind == FilterValueWord || val.Kind == FilterValueString {
+			if t, err := time.Parse(time.RFC3339, val.Raw); err == nil {
+				return t, nil
+			}
+			if t, err := time.Parse(filterDateLayout, val.Raw); err == nil {
+				return t, nil
+			}
+		}
+		return nil, filterTypeError(cmp, "value should be date (2006-01-02) or date-time (RFC3339)")
+	}
+	return nil, filterTypeError(cmp, "unsupported field type")
+}
+
+func filterTypeError(cmp FilterComparison, msg string) error {
+	return apperrors.NewValidationError("invalid filter expression",
+		[]apperrors.FieldError{{Field: cmp.Field, Message: fmt.Sprintf("%s: %s", cmp.Op, msg)}})
+}
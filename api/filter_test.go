@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestFilter(t *testing.T) {
+	schema := api.FilterSchema{
+		"hardwareId": {Type: api.FilterString},
+		"status":     {Type: api.FilterString},
+		"size":       {Type: api.FilterInt},
+		"active":     {Type: api.FilterBool},
+		"createdAt":  {Type: api.FilterTime, Field: "created_at"},
+	}
+	compile := func(expr string) (bson.D, error) {
+		ctx := echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, "/devices?filter="+url.QueryEscape(expr), nil),
+			httptest.NewRecorder())
+		return api.ParseFilterQuery(ctx, schema)
+	}
+
+	t.Run("should compile filter expression to mongo filter", func(t *testing.T) {
+		got, err := compile(`hardwareId eq "x" and (createdAt gt 2024-01-01 or not status in ("FAILED", null))`)
+		if err != nil {
+			t.Fatalf("compile returned error: %v", err)
+		}
+		expected := bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "hardwareId", Value: bson.D{{Key: "$eq", Value: "x"}}}},
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "created_at", Value: bson.D{{Key: "$gt", Value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}}},
+				bson.D{{Key: "$nor", Value: bson.A{
+					bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"FAILED", nil}}}}},
+				}}},
+			}}},
+		}}}
+		gotJSON, _ := bson.MarshalExtJSON(got, false, false)
+		expectedJSON, _ := bson.MarshalExtJSON(expected, false, false)
+		if string(gotJSON) != string(expectedJSON) {
+			t.Errorf("got %s, expected %s", gotJSON, expectedJSON)
+		}
+	})
+	t.Run("should escape regular expressions", func(t *testing.T) {
+		got, err := compile(`hardwareId startswith "a.*"`)
+		if err != nil {
+			t.Fatalf("compile returned error: %v", err)
+		}
+		if re := got[0].Value.(primitive.Regex); re.Pattern != `^a\.\*` {
+			t.Errorf("got pattern %s, expected escaped prefix", re.Pattern)
+		}
+	})
+	t.Run("should return empty filter if parameter is missing", func(t *testing.T) {
+		if got, err := compile(""); err != nil || len(got) != 0 {
+			t.Errorf("got %v, %v, expected empty filter", got, err)
+		}
+	})
+	t.Run("should reject invalid expressions", func(t *testing.T) {
+		tests := []string{
+			`$where eq "1"`,
+			`hardwareId eq {"$ne": 1}`,
+			`hardwareId $ne "x"`,
+			`secret eq "x"`,
+			`size eq "10"`,
+			`size gt 1.5`,
+			`active eq 1`,
+			`createdAt lt yesterday`,
+			`size contains "1"`,
+			`hardwareId gt null`,
+			`hardwareId eq "x" and`,
+			`(hardwareId eq "x"`,
+			`hardwareId eq "x`,
+			`hardwareId in ()`,
+			`hardwareId eq "x" size eq 1`,
+		}
+		for _, expr := range tests {
+			_, err := compile(expr)
+			if err == nil {
+				t.Errorf("%s: expected error", expr)
+				continue
+			}
+			if code := apperrors.ToAppError(err).ErrorCode; code != apperrors.ErrorDataValidation {
+				t.Errorf("%s: got error code %s, expected %s", expr, code, apperrors.ErrorDataValidation)
+			}
+		}
+	})
+}
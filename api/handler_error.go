@@ -5,26 +5,63 @@ import (
 	"reflect"
 
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
 )
 
 type errorProcessor func(error, echo.Context)
 
 // ErrorHandler is a wrapper on echo.HTTPErrorHandler
 type ErrorHandler struct {
-	Handler    echo.HTTPErrorHandler
-	processors map[string]errorProcessor
+	Handler     echo.HTTPErrorHandler
+	processors  map[string]errorProcessor
+	statusCodes map[apperrors.AppErrorCode]int
 }
 
-// NewErrorHandler sets up the mapping of error type to handler
+// NewErrorHandler sets up the mapping of error type to handler.
+// apperrors.AppError is sent as ErrorResponse with status code mapped from its ErrorCode
+// (ex. ErrorDbNoDocumentFound is 404, see MapErrorCode), not mapped codes are sent as 500,
+// other errors are sent as 500 with error message. Error is recorded on span of request (see Tracing)
 func NewErrorHandler() *ErrorHandler {
 	eh := ErrorHandler{}
 	eh.Handler = eh.errorHandlerFunc
 	eh.processors = make(map[string]errorProcessor)
 	eh.processors[errorType(&echo.HTTPError{})] = echoHTTPErrorProcessor
+	eh.processors[errorType(apperrors.AppError{})] = eh.appErrorProcessor
+	eh.processors[errorType(&apperrors.AppError{})] = eh.appErrorProcessor
+	eh.statusCodes = map[apperrors.AppErrorCode]int{
+		apperrors.ErrorDataValidation:           http.StatusBadRequest,
+		apperrors.ErrorDataSerialization:        http.StatusBadRequest,
+		apperrors.ErrorDataTooLarge:             http.StatusRequestEntityTooLarge,
+		apperrors.ErrorDataRangeNotSatisfiable:  http.StatusRequestedRangeNotSatisfiable,
+		apperrors.ErrorDbNoDocumentFound:        http.StatusNotFound,
+		apperrors.ErrorDbAlreadyExist:           http.StatusConflict,
+		apperrors.ErrorSvcEntityExists:          http.StatusConflict,
+		apperrors.ErrorDbVersionConflict:        http.StatusPreconditionFailed,
+		apperrors.ErrorSvcPreconditionFailed:    http.StatusPreconditionFailed,
+		apperrors.ErrorSvcIdempotencyKeyReused:  http.StatusUnprocessableEntity,
+		apperrors.ErrorSvcIdempotencyInProgress: http.StatusConflict,
+		apperrors.ErrorSvcRateLimited:           http.StatusTooManyRequests,
+		apperrors.ErrorSvcOverloaded:            http.StatusServiceUnavailable,
+		apperrors.ErrorSvcTimeout:               http.StatusGatewayTimeout,
+		apperrors.ErrorDbTimeout:                http.StatusGatewayTimeout,
+		apperrors.ErrorSvcQuotaExceeded:         http.StatusTooManyRequests,
+		apperrors.ErrorSvcInvalidState:          http.StatusConflict,
+	}
 	return &eh
 }
 
+// MapErrorCode sets http status code of responses to AppError with provided code
+// (http.StatusInternalServerError is used for not mapped codes)
+func (eh *ErrorHandler) MapErrorCode(code apperrors.AppErrorCode, statusCode int) {
+	eh.statusCodes[code] = statusCode
+}
+
 func (eh *ErrorHandler) errorHandlerFunc(err error, c echo.Context) {
+	if !c.Response().Committed {
+		trace.SpanFromContext(c.Request().Context()).RecordError(err)
+	}
 	p, found := eh.processors[errorType(err)]
 	if !found {
 		p = defaultErrorProcessor
@@ -40,6 +77,19 @@ func echoHTTPErrorProcessor(err error, c echo.Context) {
 	defaultErrorProcessor(err, c)
 }
 
+func (eh *ErrorHandler) appErrorProcessor(err error, c echo.Context) {
+	if p, ok := err.(*apperrors.AppError); ok && p != nil {
+		// errors.As of AppError value does not match pointer
+		err = *p
+	}
+	appErr := apperrors.ToAppError(err)
+	code, found := eh.statusCodes[appErr.ErrorCode]
+	if !found {
+		code = http.StatusInternalServerError
+	}
+	sendResponse(code, NewErrorResponse(GetRequestContext(c), code, *appErr), c)
+}
+
 func clientError(statusCode int) bool {
 	return statusCode < http.StatusInternalServerError && statusCode >= http.StatusBadRequest
 }
@@ -0,0 +1,55 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestErrorHandler(t *testing.T) {
+	eh := api.NewErrorHandler()
+	eh.MapErrorCode(apperrors.ErrorSvcInvalidState, http.StatusUnprocessableEntity)
+	e := echo.New()
+	e.HTTPErrorHandler = eh.Handler
+	serve := func(err error) *httptest.ResponseRecorder {
+		e.GET("/", func(echo.Context) error { return err })
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	t.Run("should map AppError code to status code", func(t *testing.T) {
+		tests := []struct {
+			err      error
+			expected int
+		}{
+			{apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "not found"), http.StatusNotFound},
+			{&apperrors.AppError{ErrorCode: apperrors.ErrorDbAlreadyExist, Description: "exists"}, http.StatusConflict},
+			{apperrors.NewAppError(apperrors.ErrorSvcInvalidState, "invalid state"), http.StatusUnprocessableEntity},
+			{apperrors.NewAppError(apperrors.ErrorDbOperation, "failed"), http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			rec := serve(tt.err)
+			if rec.Code != tt.expected {
+				t.Errorf("%v: got status %d, expected %d", tt.err, rec.Code, tt.expected)
+			}
+			var res api.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.StatusCode != tt.expected || res.ErrorCode == "" {
+				t.Errorf("%v: got response %s", tt.err, rec.Body.String())
+			}
+		}
+	})
+	t.Run("should send other errors as internal server error", func(t *testing.T) {
+		rec := serve(errors.New("failed"))
+		if rec.Code != http.StatusInternalServerError || rec.Body.String() != "\"failed\"\n" {
+			t.Errorf("got status %d and body %q", rec.Code, rec.Body.String())
+		}
+	})
+}
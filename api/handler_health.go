@@ -2,49 +2,211 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"net/http"
-	"sync"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	// HealthCheckParam is route parameter name of single health check endpoint
+	// (ex. ReadinessPath + "/:check")
+	HealthCheckParam = "check"
+
+	queryVerbose = "verbose"
+	queryExclude = "exclude"
+)
+
 // HealthzHandler is a k8s liveness endpoint
 func HealthzHandler(ctx echo.Context) error {
+	if isVerbose(ctx) {
+		return ctx.String(http.StatusOK, "[+]ping ok\nhealth check passed\n")
+	}
 	return ctx.JSON(http.StatusOK, HealthStatusResponse{Status: StatusHealthy})
 }
 
-// ReadyzHandler is a k8s readiness endpoint
+// ReadyzHandler is a k8s readiness endpoint,
+// each check is critical and runs with DefaultHealthCheckTimeout,
+// any not healthy entry (including degraded one) makes service unhealthy with 503 status
 func ReadyzHandler(fns ...func(ctx context.Context) HealthEntryStatus) func(echo.Context) error {
-	return func(ctx echo.Context) error {
-		entries := make([]HealthEntryStatus, 0, len(fns))
-		ch := make(chan HealthEntryStatus, len(fns))
-		var wg sync.WaitGroup
-		healthy := true
-
-		for _, fn := range fns {
-			wg.Add(1)
-			go func(f func(context.Context) HealthEntryStatus, w *sync.WaitGroup) {
-				defer w.Done()
-				ch <- f(ctx.Request().Context())
-			}(fn, &wg)
-		}
-		wg.Wait()
-		close(ch)
-
-		for entry := range ch {
-			healthy = healthy && entry.Status == StatusHealthy
-			entries = append(entries, entry)
-		}
-
-		if healthy {
-			return ctx.JSON(http.StatusOK, HealthStatusResponse{
-				Status:  StatusHealthy,
-				Entries: entries,
-			})
-		}
-		return ctx.JSON(http.StatusServiceUnavailable, HealthStatusResponse{
-			Status:  StatusUnhealthy,
-			Entries: entries,
+	reg := NewHealthRegistry()
+	for i, fn := range fns {
+		// names are unique, so registration fails only for nil functions which are skipped
+		_ = reg.Register(HealthCheck{
+			Name:  fmt.Sprintf("check-%d", i),
+			Check: fn,
 		})
 	}
+	return healthReportHandler(strictReporter{reg})
+}
+
+// healthReporter is source of service health state
+type healthReporter interface {
+	// report returns health state of all checks except excluded ones
+	report(ctx context.Context, exclude ...string) HealthStatusResponse
+	// Names returns names of all checks
+	Names() []string
+}
+
+// HealthRegistryHandler is a k8s readiness endpoint running all checks of HealthRegistry
+// it supports kube-apiserver style query parameters:
+// ?verbose returns text report per check, ?exclude=<name> skips check (can be repeated)
+func HealthRegistryHandler(reg *HealthRegistry) echo.HandlerFunc {
+	return healthReportHandler(reg)
+}
+
+// HealthMonitorHandler is a k8s readiness endpoint answering from cached state of HealthMonitor,
+// it supports the same query parameters as HealthRegistryHandler
+func HealthMonitorHandler(m *HealthMonitor) echo.HandlerFunc {
+	return healthReportHandler(m)
+}
+
+// LifecycleReadinessHandler is a k8s readiness endpoint answering from cached state of HealthMonitor
+// with lifecycle check evaluated on each request, so startup completion and draining are reported
+// without waiting for next monitor run
+func LifecycleReadinessHandler(l *Lifecycle, m *HealthMonitor) echo.HandlerFunc {
+	return healthReportHandler(lifecycleReporter{HealthMonitor: m, lifecycle: l})
+}
+
+// HealthHistoryHandler is an endpoint returning recent results of health checks collected by HealthMonitor
+func HealthHistoryHandler(m *HealthMonitor) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, m.History())
+	}
+}
+
+// strictReporter is HealthRegistry reporting degraded state as unhealthy
+type strictReporter struct {
+	*HealthRegistry
+}
+
+// report implements healthReporter interface
+func (r strictReporter) report(ctx context.Context, exclude ...string) HealthStatusResponse {
+	resp := r.HealthRegistry.report(ctx, exclude...)
+	if resp.Status != StatusHealthy {
+		resp.Status = StatusUnhealthy
+	}
+	return resp
+}
+
+// lifecycleReporter is HealthMonitor reporting live state of Lifecycle
+type lifecycleReporter struct {
+	*HealthMonitor
+	lifecycle *Lifecycle
+}
+
+// report implements healthReporter interface
+func (r lifecycleReporter) report(ctx context.Context, exclude ...string) HealthStatusResponse {
+	resp := r.Status(exclude...)
+	if contains(exclude, lifecycleCheckName) {
+		return resp
+	}
+	live := r.lifecycle.ReadinessCheck().Check(ctx)
+	live.Name = lifecycleCheckName
+	for i := range resp.Entries {
+		if resp.Entries[i].Name == lifecycleCheckName {
+			resp.Entries[i] = live
+			resp.Status = r.reg.aggregate(resp.Entries)
+			return resp
+		}
+	}
+	// lifecycle check was not run yet by monitor
+	resp.Entries = append([]HealthEntryStatus{live}, resp.Entries...)
+	if live.Status == StatusUnhealthy {
+		resp.Status = StatusUnhealthy
+	}
+	return resp
+}
+
+func healthReportHandler(reporter healthReporter) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		exclude := ctx.QueryParams()[queryExclude]
+		resp := reporter.report(ctx.Request().Context(), exclude...)
+		code := healthStatusCode(resp.Status)
+		if !isVerbose(ctx) {
+			return ctx.JSON(code, resp)
+		}
+
+		var b strings.Builder
+		for _, entry := range resp.Entries {
+			b.WriteString(verboseEntry(entry))
+		}
+		if unknown := unknownChecks(reporter.Names(), exclude); len(unknown) > 0 {
+			fmt.Fprintf(&b, "warn: some health checks cannot be excluded: no matches for %s\n",
+				strings.Join(unknown, ","))
+		}
+		if resp.Status == StatusUnhealthy {
+			b.WriteString("health check failed\n")
+		} else {
+			b.WriteString("health check passed\n")
+		}
+		return ctx.String(code, b.String())
+	}
+}
+
+// HealthCheckHandler is an endpoint probing single check of HealthRegistry
+// looked up by HealthCheckParam route parameter
+func HealthCheckHandler(reg *HealthRegistry) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		name := ctx.Param(HealthCheckParam)
+		entry, found := reg.CheckOne(ctx.Request().Context(), name)
+		if !found {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("health check %s not found", name))
+		}
+		code := http.StatusOK
+		if entry.Status == StatusUnhealthy {
+			code = http.StatusServiceUnavailable
+		}
+		if isVerbose(ctx) {
+			return ctx.String(code, verboseEntry(entry))
+		}
+		return ctx.JSON(code, entry)
+	}
+}
+
+// healthStatusCode converts service Status to http status code
+func healthStatusCode(status Status) int {
+	if status == StatusUnhealthy {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func isVerbose(ctx echo.Context) bool {
+	_, found := ctx.QueryParams()[queryVerbose]
+	return found
+}
+
+// verboseEntry formats check result as kube-apiserver verbose report line
+func verboseEntry(entry HealthEntryStatus) string {
+	switch entry.Status {
+	case StatusHealthy:
+		return fmt.Sprintf("[+]%s ok (%s)\n", entry.Name, entry.Duration)
+	case StatusDegraded:
+		return fmt.Sprintf("[-]%s degraded (%s)\n", entry.Name, entry.Duration)
+	default:
+		return fmt.Sprintf("[-]%s failed (%s)\n", entry.Name, entry.Duration)
+	}
+}
+
+// unknownChecks returns names which are not in registered list
+func unknownChecks(registered []string, names []string) []string {
+	var unknown []string
+	for _, name := range names {
+		if !contains(registered, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// StartupzHandler is a k8s startup endpoint passing after all warm-up hooks of Lifecycle completed
+func StartupzHandler(l *Lifecycle) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if !l.Started() {
+			return ctx.JSON(http.StatusServiceUnavailable, HealthStatusResponse{Status: StatusUnhealthy})
+		}
+		return ctx.JSON(http.StatusOK, HealthStatusResponse{Status: StatusHealthy})
+	}
 }
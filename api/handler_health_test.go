@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestHealthRegistryHandler(t *testing.T) {
+	reg := api.NewHealthRegistry()
+	_ = reg.Register(
+		api.HealthCheck{Name: "db", Check: func(context.Context) api.HealthEntryStatus {
+			return api.HealthEntryStatus{Status: api.StatusHealthy}
+		}},
+		api.HealthCheck{Name: "queue", Check: func(context.Context) api.HealthEntryStatus {
+			return api.HealthEntryStatus{Status: api.StatusUnhealthy}
+		}},
+	)
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.GET(api.ReadinessPath, api.HealthRegistryHandler(reg))
+	e.GET(api.ReadinessPath+"/:"+api.HealthCheckParam, api.HealthCheckHandler(reg))
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("should return verbose text report", func(t *testing.T) {
+		rec := get(api.ReadinessPath + "?verbose")
+		body := rec.Body.String()
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusServiceUnavailable)
+		}
+		for _, line := range []string{"[+]db ok", "[-]queue failed", "health check failed"} {
+			if !strings.Contains(body, line) {
+				t.Errorf("verbose report %q does not contain %q", body, line)
+			}
+		}
+	})
+	t.Run("should skip excluded checks", func(t *testing.T) {
+		rec := get(api.ReadinessPath + "?exclude=queue")
+		if rec.Code != http.StatusOK {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusOK)
+		}
+		var resp api.HealthStatusResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal returned error: %v", err)
+		}
+		if len(resp.Entries) != 1 || resp.Entries[0].Name != "db" || resp.Entries[0].LastSuccess == nil {
+			t.Errorf("got %v, expected only db entry with last success time", resp.Entries)
+		}
+		if !strings.Contains(rec.Body.String(), `"duration":"`) {
+			t.Errorf("got %s, expected duration serialized as string", rec.Body.String())
+		}
+	})
+	t.Run("should probe single check", func(t *testing.T) {
+		if rec := get(api.ReadinessPath + "/db"); rec.Code != http.StatusOK {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusOK)
+		}
+		if rec := get(api.ReadinessPath + "/queue"); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusServiceUnavailable)
+		}
+		if rec := get(api.ReadinessPath + "/unknown"); rec.Code != http.StatusNotFound {
+			t.Errorf("got %d, expected %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestReadyzHandler(t *testing.T) {
+	t.Run("should report degraded entry as unhealthy", func(t *testing.T) {
+		e := echo.New()
+		e.GET(api.ReadinessPath, api.ReadyzHandler(
+			func(context.Context) api.HealthEntryStatus { return api.HealthEntryStatus{Status: api.StatusHealthy} },
+			func(context.Context) api.HealthEntryStatus { return api.HealthEntryStatus{Status: api.StatusDegraded} },
+		))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.ReadinessPath, nil))
+		var resp api.HealthStatusResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal returned error: %v", err)
+		}
+		if rec.Code != http.StatusServiceUnavailable || resp.Status != api.StatusUnhealthy || len(resp.Entries) != 2 {
+			t.Errorf("got %d %+v, expected %d with unhealthy status", rec.Code, resp, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler is a prometheus scrape endpoint
+func MetricsHandler(m *Metrics) echo.HandlerFunc {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry: m.registry,
+	})
+	return echo.WrapHandler(h)
+}
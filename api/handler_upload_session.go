@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// UploadSessionParam is path parameter with upload session id
+	UploadSessionParam = "uploadId"
+	// UploadPartParam is path parameter with upload part number
+	UploadPartParam = "partNumber"
+)
+
+// UploadTarget returns sink of content of completed upload session (ex. FileUploadSink of image file)
+type UploadTarget func(ctx echo.Context, s *UploadSession) (UploadSink, error)
+
+// RegisterUploadSessionRoutes adds multipart upload endpoints to group:
+//
+//	POST   /                              - create session (CreateUploadSessionRequest)
+//	GET    /:uploadId                     - session status with uploaded parts
+//	PUT    /:uploadId/parts/:partNumber   - upload part (see Upload for digest headers)
+//	POST   /:uploadId/complete            - concatenate parts to target and verify content digest
+//	DELETE /:uploadId                     - abort session
+//
+// Concatenation of large uploads exceeds default deadline of Timeout middleware,
+// deadline of complete route should be disabled with TimeoutConfig.Routes
+// (ex. "POST /uploads/:uploadId/complete": 0), completion has own deadline UploadSessionConfig.CompleteTimeout
+func RegisterUploadSessionRoutes(g *echo.Group, m *UploadSessions, target UploadTarget) {
+	h := uploadSessionHandlers{sessions: m, target: target}
+	session := fmt.Sprintf("/:%s", UploadSessionParam)
+	g.POST("", h.create)
+	g.GET(session, h.get)
+	g.PUT(fmt.Sprintf("%s/parts/:%s", session, UploadPartParam), h.uploadPart)
+	g.POST(session+"/complete", h.complete)
+	g.DELETE(session, h.abort)
+}
+
+type uploadSessionHandlers struct {
+	sessions *UploadSessions
+	target   UploadTarget
+}
+
+func (h uploadSessionHandlers) create(c echo.Context) error {
+	var req CreateUploadSessionRequest
+	if err := BindJSON(c, &req); err != nil {
+		return err
+	}
+	s, err := h.sessions.Create(GetRequestContext(c), GetNamespace(c), req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, s)
+}
+
+func (h uploadSessionHandlers) get(c echo.Context) error {
+	s, err := h.sessions.Get(GetRequestContext(c), GetNamespace(c), c.Param(UploadSessionParam))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+func (h uploadSessionHandlers) uploadPart(c echo.Context) error {
+	number, err := strconv.Atoi(c.Param(UploadPartParam))
+	if err != nil {
+		return apperrors.NewValidationError("invalid upload part", []apperrors.FieldError{{
+			Field:   UploadPartParam,
+			Message: "should be integer",
+		}})
+	}
+	part, err := h.sessions.UploadPart(c, c.Param(UploadSessionParam), number)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, part)
+}
+
+func (h uploadSessionHandlers) complete(c echo.Context) error {
+	ctx, ns := GetRequestContext(c), GetNamespace(c)
+	s, err := h.sessions.Get(ctx, ns, c.Param(UploadSessionParam))
+	if err != nil {
+		return err
+	}
+	sink, err := h.target(c, s)
+	if err != nil {
+		return err
+	}
+	if s, err = h.sessions.Complete(ctx, ns, s.ID, sink); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+func (h uploadSessionHandlers) abort(c echo.Context) error {
+	if err := h.sessions.Abort(GetRequestContext(c), GetNamespace(c), c.Param(UploadSessionParam)); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
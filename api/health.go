@@ -1,5 +1,10 @@
 package api
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Status of service
 type Status string
 
@@ -10,18 +15,64 @@ const (
 	// ReadinessPath endpoint default path
 	ReadinessPath = "/readyz"
 
+	// StartupPath endpoint default path
+	StartupPath = "/startupz"
+
+	// HealthHistoryPath endpoint default path
+	HealthHistoryPath = LivenessPath + "/history"
+
 	// StatusHealthy means that service in health state
 	StatusHealthy = Status("StatusHealthy")
 
-	// StatusUnhealthy means that service is in degraded state
+	// StatusUnhealthy means that service is in unhealthy state
 	StatusUnhealthy = Status("StatusUnhealthy")
+
+	// StatusDegraded means that service is working but some non-critical dependencies failed
+	StatusDegraded = Status("StatusDegraded")
 )
 
 // HealthEntryStatus is status of external dependency like db or queue
 type HealthEntryStatus struct {
+	Name     string      `json:"name,omitempty"`
 	Status   Status      `json:"status"`
 	Data     interface{} `json:"data"`
 	Resource string      `json:"resource"`
+	// Duration is execution time of check (serialized as string, ex. "1.5ms")
+	Duration Duration `json:"duration,omitempty"`
+	// LastSuccess is time of last healthy result of check
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// Duration is time.Duration serialized to JSON as string (ex. "1.5ms")
+type Duration time.Duration
+
+// String returns duration formatted as time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface, duration string or number of nanoseconds is accepted
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err = json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
 }
 
 // HealthStatusResponse response of health endpoints
@@ -29,3 +80,19 @@ type HealthStatusResponse struct {
 	Status  Status              `json:"status"`
 	Entries []HealthEntryStatus `json:"entries,omitempty"`
 }
+
+// HealthCheckHistory is recent results of single health check
+type HealthCheckHistory struct {
+	Name string `json:"name"`
+	// Flapping is true if check changed its status too often
+	Flapping bool `json:"flapping"`
+	// Transitions is count of status changes within Results
+	Transitions int `json:"transitions"`
+	// Results ordered from oldest to newest
+	Results []HealthEntryStatus `json:"results"`
+}
+
+// HealthHistoryResponse response of health history endpoint
+type HealthHistoryResponse struct {
+	Checks []HealthCheckHistory `json:"checks"`
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHealthMonitorInterval is default interval between health checks runs
+	DefaultHealthMonitorInterval = 10 * time.Second
+	// DefaultHealthHistorySize is default count of kept results per check
+	DefaultHealthHistorySize = 20
+	// DefaultHealthFlapThreshold is default count of status changes within history marking check as flapping
+	DefaultHealthFlapThreshold = 4
+)
+
+// HealthMonitorConfig is configuration of background health monitor
+type HealthMonitorConfig struct {
+	// Interval between health checks runs
+	Interval time.Duration
+	// HistorySize is count of kept results per check
+	HistorySize int
+	// FlapThreshold is count of status changes within history marking check as flapping
+	FlapThreshold int
+}
+
+// HealthMonitor runs checks of HealthRegistry in background and keeps their recent results
+type HealthMonitor struct {
+	reg     *HealthRegistry
+	cfg     HealthMonitorConfig
+	mu      sync.RWMutex
+	last    []HealthEntryStatus
+	checked bool
+	history map[string]*healthRing
+}
+
+// healthRing is ring buffer of check results
+type healthRing struct {
+	results []HealthEntryStatus
+	next    int
+	full    bool
+}
+
+// NewHealthMonitor creates HealthMonitor of HealthRegistry checks
+func NewHealthMonitor(reg *HealthRegistry, cfg HealthMonitorConfig) *HealthMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthMonitorInterval
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHealthHistorySize
+	}
+	if cfg.FlapThreshold <= 0 {
+		cfg.FlapThreshold = DefaultHealthFlapThreshold
+	}
+	return &HealthMonitor{
+		reg:     reg,
+		cfg:     cfg,
+		history: make(map[string]*healthRing),
+	}
+}
+
+// Run checks health on interval until ctx is canceled
+func (m *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		m.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh runs all registered checks and stores their results
+func (m *HealthMonitor) Refresh(ctx context.Context) {
+	resp := m.reg.Check(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = resp.Entries
+	m.checked = true
+	for _, entry := range resp.Entries {
+		ring, ok := m.history[entry.Name]
+		if !ok {
+			ring = &healthRing{results: make([]HealthEntryStatus, m.cfg.HistorySize)}
+			m.history[entry.Name] = ring
+		}
+		ring.add(entry)
+	}
+}
+
+// Status returns health state from last run (except excluded checks)
+func (m *HealthMonitor) Status(exclude ...string) HealthStatusResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.checked {
+		return HealthStatusResponse{Status: StatusUnhealthy}
+	}
+	entries := make([]HealthEntryStatus, 0, len(m.last))
+	for _, entry := range m.last {
+		if !contains(exclude, entry.Name) {
+			entries = append(entries, entry)
+		}
+	}
+	return HealthStatusResponse{
+		Status:  m.reg.aggregate(entries),
+		Entries: entries,
+	}
+}
+
+// History returns recent results of all checks
+func (m *HealthMonitor) History() HealthHistoryResponse {
+	names := m.reg.Names()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	resp := HealthHistoryResponse{Checks: make([]HealthCheckHistory, 0, len(names))}
+	for _, name := range names {
+		ring, ok := m.history[name]
+		if !ok {
+			continue
+		}
+		results := ring.list()
+		transitions := countTransitions(results)
+		resp.Checks = append(resp.Checks, HealthCheckHistory{
+			Name:        name,
+			Flapping:    transitions >= m.cfg.FlapThreshold,
+			Transitions: transitions,
+			Results:     results,
+		})
+	}
+	return resp
+}
+
+// Flapping returns true if check changed its status too often
+func (m *HealthMonitor) Flapping(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ring, ok := m.history[name]
+	return ok && countTransitions(ring.list()) >= m.cfg.FlapThreshold
+}
+
+// Names returns names of monitored checks
+func (m *HealthMonitor) Names() []string {
+	return m.reg.Names()
+}
+
+// report implements healthReporter interface
+func (m *HealthMonitor) report(_ context.Context, exclude ...string) HealthStatusResponse {
+	return m.Status(exclude...)
+}
+
+func (r *healthRing) add(entry HealthEntryStatus) {
+	r.results[r.next] = entry
+	r.next = (r.next + 1) % len(r.results)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns results ordered from oldest to newest
+func (r *healthRing) list() []HealthEntryStatus {
+	if !r.full {
+		return append([]HealthEntryStatus(nil), r.results[:r.next]...)
+	}
+	res := make([]HealthEntryStatus, 0, len(r.results))
+	res = append(res, r.results[r.next:]...)
+	return append(res, r.results[:r.next]...)
+}
+
+func countTransitions(results []HealthEntryStatus) int {
+	cnt := 0
+	for i := 1; i < len(results); i++ {
+		if results[i].Status != results[i-1].Status {
+			cnt++
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	newMonitor := func(statuses ...api.Status) *api.HealthMonitor {
+		calls := 0
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(api.HealthCheck{Name: "db", Check: func(context.Context) api.HealthEntryStatus {
+			st := statuses[calls%len(statuses)]
+			calls++
+			return api.HealthEntryStatus{Status: st}
+		}})
+		return api.NewHealthMonitor(reg, api.HealthMonitorConfig{HistorySize: 5, FlapThreshold: 3})
+	}
+
+	t.Run("should be unhealthy before first run", func(t *testing.T) {
+		m := newMonitor(api.StatusHealthy)
+		if st := m.Status().Status; st != api.StatusUnhealthy {
+			t.Errorf("got %s, expected %s", st, api.StatusUnhealthy)
+		}
+	})
+	t.Run("should answer from cached state", func(t *testing.T) {
+		m := newMonitor(api.StatusHealthy, api.StatusUnhealthy)
+		m.Refresh(context.Background())
+		for i := 0; i < 3; i++ {
+			if st := m.Status().Status; st != api.StatusHealthy {
+				t.Errorf("got %s, expected %s", st, api.StatusHealthy)
+			}
+		}
+	})
+	t.Run("should keep last results in ring buffer", func(t *testing.T) {
+		m := newMonitor(api.StatusHealthy)
+		for i := 0; i < 7; i++ {
+			m.Refresh(context.Background())
+		}
+		hist := m.History()
+		if len(hist.Checks) != 1 || len(hist.Checks[0].Results) != 5 {
+			t.Errorf("got %v, expected 5 results of db check", hist)
+		}
+		if m.Flapping("db") {
+			t.Error("stable check should not be flapping")
+		}
+	})
+	t.Run("should detect flapping check", func(t *testing.T) {
+		m := newMonitor(api.StatusHealthy, api.StatusUnhealthy)
+		for i := 0; i < 4; i++ {
+			m.Refresh(context.Background())
+		}
+		hist := m.History()
+		if !hist.Checks[0].Flapping || hist.Checks[0].Transitions != 3 {
+			t.Errorf("got %v, expected flapping check with 3 transitions", hist.Checks[0])
+		}
+	})
+}
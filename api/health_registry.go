@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// DefaultHealthCheckTimeout is default timeout of single health check
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckFunc checks state of external dependency like db or queue
+type HealthCheckFunc func(ctx context.Context) HealthEntryStatus
+
+// HealthCheck is named check of external dependency
+type HealthCheck struct {
+	// Name is unique name of check
+	Name string
+	// Check is function checking dependency state
+	Check HealthCheckFunc
+	// Timeout of check execution (DefaultHealthCheckTimeout if not set)
+	Timeout time.Duration
+	// CacheTTL is duration the check result is reused for (no caching if not set)
+	CacheTTL time.Duration
+	// NonCritical failure of check reports service as degraded instead of unhealthy
+	NonCritical bool
+}
+
+// HealthRegistry is registry of service health checks
+type HealthRegistry struct {
+	mu     sync.RWMutex
+	checks []*registeredCheck
+}
+
+type registeredCheck struct {
+	HealthCheck
+	mu          sync.Mutex
+	cached      HealthEntryStatus
+	expires     time.Time
+	lastSuccess *time.Time
+	inflight    *checkCall
+}
+
+// checkCall is in-flight check execution shared by concurrent probes
+type checkCall struct {
+	done  chan struct{}
+	entry HealthEntryStatus
+}
+
+// NewHealthRegistry creates empty HealthRegistry
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Register adds health checks to registry
+func (r *HealthRegistry) Register(checks ...HealthCheck) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, hc := range checks {
+		if hc.Name == "" || hc.Check == nil {
+			return apperrors.NewAppError(apperrors.ErrorDataValidation,
+				"health check should have name and check function")
+		}
+		if r.find(hc.Name) != nil {
+			return apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("health check %s already registered", hc.Name))
+		}
+		if hc.Timeout <= 0 {
+			hc.Timeout = DefaultHealthCheckTimeout
+		}
+		r.checks = append(r.checks, &registeredCheck{HealthCheck: hc})
+	}
+	return nil
+}
+
+// Check runs registered health checks concurrently (except excluded by name) and aggregates their results
+func (r *HealthRegistry) Check(ctx context.Context, exclude ...string) HealthStatusResponse {
+	checks := r.list(exclude...)
+	entries := make([]HealthEntryStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func(i int, hc *registeredCheck) {
+			defer wg.Done()
+			entries[i] = hc.run(ctx)
+		}(i, hc)
+	}
+	wg.Wait()
+
+	return HealthStatusResponse{
+		Status:  r.aggregate(entries),
+		Entries: entries,
+	}
+}
+
+// CheckOne runs single health check looked up by name
+func (r *HealthRegistry) CheckOne(ctx context.Context, name string) (HealthEntryStatus, bool) {
+	r.mu.RLock()
+	hc := r.find(name)
+	r.mu.RUnlock()
+	if hc == nil {
+		return HealthEntryStatus{}, false
+	}
+	return hc.run(ctx), true
+}
+
+// Names returns names of registered health checks
+func (r *HealthRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.checks))
+	for _, hc := range r.checks {
+		names = append(names, hc.Name)
+	}
+	return names
+}
+
+func (r *HealthRegistry) list(exclude ...string) []*registeredCheck {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checks := make([]*registeredCheck, 0, len(r.checks))
+	for _, hc := range r.checks {
+		if !contains(exclude, hc.Name) {
+			checks = append(checks, hc)
+		}
+	}
+	return checks
+}
+
+func (r *HealthRegistry) find(name string) *registeredCheck {
+	for _, hc := range r.checks {
+		if hc.Name == name {
+			return hc
+		}
+	}
+	return nil
+}
+
+// run executes check with timeout, panics are reported as unhealthy entry,
+// concurrent probes wait for result of the same in-flight execution
+func (hc *registeredCheck) run(ctx context.Context) HealthEntryStatus {
+	hc.mu.Lock()
+	now := time.Now()
+	if now.Before(hc.expires) {
+		entry := hc.cached
+		hc.mu.Unlock()
+		return entry
+	}
+	if call := hc.inflight; call != nil {
+		hc.mu.Unlock()
+		<-call.done
+		return call.entry
+	}
+	call := &checkCall{done: make(chan struct{})}
+	hc.inflight = call
+	hc.mu.Unlock()
+
+	// shared result should not depend on cancellation of the first probe
+	entry := hc.execute(context.WithoutCancel(ctx), now)
+
+	hc.mu.Lock()
+	if entry.Status == StatusHealthy {
+		ts := now
+		hc.lastSuccess = &ts
+	}
+	entry.LastSuccess = hc.lastSuccess
+	if hc.CacheTTL > 0 {
+		hc.cached = entry
+		hc.expires = now.Add(hc.CacheTTL)
+	}
+	hc.inflight = nil
+	hc.mu.Unlock()
+
+	call.entry = entry
+	close(call.done)
+	return entry
+}
+
+// execute runs check with timeout
+func (hc *registeredCheck) execute(ctx context.Context, now time.Time) HealthEntryStatus {
+	ctxCheck, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+	ch := make(chan HealthEntryStatus, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				ch <- HealthEntryStatus{
+					Status: StatusUnhealthy,
+					Data:   fmt.Sprintf("health check panicked: %v", p),
+				}
+			}
+		}()
+		ch <- hc.Check(ctxCheck)
+	}()
+
+	var entry HealthEntryStatus
+	select {
+	case entry = <-ch:
+	case <-ctxCheck.Done():
+		entry = HealthEntryStatus{
+			Status: StatusUnhealthy,
+			Data:   fmt.Sprintf("health check did not complete in %s", hc.Timeout),
+		}
+	}
+	entry.Name = hc.Name
+	if entry.Resource == "" {
+		entry.Resource = hc.Name
+	}
+	entry.Duration = Duration(time.Since(now))
+	return entry
+}
+
+// aggregate returns overall service status from check results
+func (r *HealthRegistry) aggregate(entries []HealthEntryStatus) Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := StatusHealthy
+	for _, entry := range entries {
+		hc := r.find(entry.Name)
+		switch {
+		case entry.Status == StatusHealthy:
+		case (hc != nil && hc.NonCritical) || entry.Status == StatusDegraded:
+			status = StatusDegraded
+		default:
+			return StatusUnhealthy
+		}
+	}
+	return status
+}
+
+// report implements healthReporter interface
+func (r *HealthRegistry) report(ctx context.Context, exclude ...string) HealthStatusResponse {
+	return r.Check(ctx, exclude...)
+}
+
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestHealthRegistry(t *testing.T) {
+	healthy := func(context.Context) api.HealthEntryStatus {
+		return api.HealthEntryStatus{Status: api.StatusHealthy}
+	}
+	unhealthy := func(context.Context) api.HealthEntryStatus {
+		return api.HealthEntryStatus{Status: api.StatusUnhealthy}
+	}
+	hung := func(ctx context.Context) api.HealthEntryStatus {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return api.HealthEntryStatus{Status: api.StatusHealthy}
+	}
+	panicking := func(context.Context) api.HealthEntryStatus {
+		panic("boom")
+	}
+	probe := func(reg *api.HealthRegistry) int {
+		e := echo.New()
+		e.GET(api.ReadinessPath, api.HealthRegistryHandler(reg))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.ReadinessPath, nil))
+		return rec.Code
+	}
+
+	t.Run("should reject duplicated check names", func(t *testing.T) {
+		reg := api.NewHealthRegistry()
+		if err := reg.Register(api.HealthCheck{Name: "db", Check: healthy}); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+		if err := reg.Register(api.HealthCheck{Name: "db", Check: healthy}); err == nil {
+			t.Error("Register should fail on duplicated name")
+		}
+	})
+	t.Run("should report hung check as unhealthy after timeout", func(t *testing.T) {
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(api.HealthCheck{Name: "db", Check: hung, Timeout: 10 * time.Millisecond})
+		start := time.Now()
+		resp := reg.Check(context.Background())
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("Check should not wait for hung check")
+		}
+		if resp.Status != api.StatusUnhealthy || resp.Entries[0].Name != "db" {
+			t.Errorf("got %v, expected unhealthy db entry", resp)
+		}
+	})
+	t.Run("should report panicked check as unhealthy", func(t *testing.T) {
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(api.HealthCheck{Name: "queue", Check: panicking})
+		if code := probe(reg); code != http.StatusServiceUnavailable {
+			t.Errorf("got %d, expected %d", code, http.StatusServiceUnavailable)
+		}
+	})
+	t.Run("should report failed non-critical check as degraded", func(t *testing.T) {
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(
+			api.HealthCheck{Name: "db", Check: healthy},
+			api.HealthCheck{Name: "cache", Check: unhealthy, NonCritical: true},
+		)
+		resp := reg.Check(context.Background())
+		if resp.Status != api.StatusDegraded {
+			t.Errorf("got %s, expected %s", resp.Status, api.StatusDegraded)
+		}
+		if code := probe(reg); code != http.StatusOK {
+			t.Errorf("got %d, expected %d", code, http.StatusOK)
+		}
+	})
+	t.Run("should reuse cached result within ttl", func(t *testing.T) {
+		calls := 0
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(api.HealthCheck{
+			Name:     "db",
+			CacheTTL: time.Minute,
+			Check: func(context.Context) api.HealthEntryStatus {
+				calls++
+				return api.HealthEntryStatus{Status: api.StatusHealthy}
+			},
+		})
+		reg.Check(context.Background())
+		reg.Check(context.Background())
+		if calls != 1 {
+			t.Errorf("check was called %d times, expected 1", calls)
+		}
+	})
+	t.Run("should share in-flight check between concurrent probes", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		reg := api.NewHealthRegistry()
+		_ = reg.Register(api.HealthCheck{
+			Name: "db",
+			Check: func(context.Context) api.HealthEntryStatus {
+				calls.Add(1)
+				<-release
+				return api.HealthEntryStatus{Status: api.StatusHealthy}
+			},
+		})
+		var wg sync.WaitGroup
+		entries := make([]api.HealthEntryStatus, 5)
+		for i := range entries {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				entries[i], _ = reg.CheckOne(context.Background(), "db")
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if calls.Load() != 1 {
+			t.Errorf("check was called %d times, expected 1", calls.Load())
+		}
+		for _, entry := range entries {
+			if entry.Status != api.StatusHealthy {
+				t.Errorf("got %s, expected %s", entry.Status, api.StatusHealthy)
+			}
+		}
+	})
+}
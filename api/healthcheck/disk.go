@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+var errNotEnoughSpace = errors.New("not enough free disk space")
+
+// DiskStatus is filesystem health check details
+type DiskStatus struct {
+	// Path is checked directory
+	Path string `json:"path"`
+	// Free is count of bytes available to unprivileged user
+	Free uint64 `json:"free"`
+	// Total is filesystem size in bytes
+	Total uint64 `json:"total"`
+	// MinFree is required count of free bytes
+	MinFree uint64 `json:"minFree"`
+	// Writable is true if test file was created in directory
+	Writable bool `json:"writable"`
+	// Error is check error description
+	Error string `json:"error,omitempty"`
+}
+
+// Disk creates health check of directory (ex. upload directory),
+// check is unhealthy if directory is not writable or has less than minFree bytes available
+func Disk(name, path string, minFree uint64) api.HealthCheck {
+	return api.HealthCheck{
+		Name: name,
+		Check: func(context.Context) api.HealthEntryStatus {
+			st := DiskStatus{Path: path, MinFree: minFree}
+			unhealthy := func(err error) api.HealthEntryStatus {
+				st.Error = err.Error()
+				return api.HealthEntryStatus{Status: api.StatusUnhealthy, Data: st, Resource: path}
+			}
+
+			var err error
+			if st.Free, st.Total, err = diskUsage(path); err != nil {
+				return unhealthy(err)
+			}
+			if err = checkWritable(path); err != nil {
+				return unhealthy(err)
+			}
+			st.Writable = true
+			if st.Free < minFree {
+				return unhealthy(errNotEnoughSpace)
+			}
+			return api.HealthEntryStatus{Status: api.StatusHealthy, Data: st, Resource: path}
+		},
+	}
+}
+
+// checkWritable creates and removes temporary file in directory
+func checkWritable(path string) error {
+	f, err := os.CreateTemp(path, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	if err = f.Close(); err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package healthcheck
+
+import "syscall"
+
+// diskUsage returns available to unprivileged user and total bytes of filesystem
+func diskUsage(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err = syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	bsize := uint64(st.Bsize)
+	return uint64(st.Bavail) * bsize, uint64(st.Blocks) * bsize, nil
+}
@@ -0,0 +1,2 @@
+// Package healthcheck contains ready-made api.HealthCheck factories for common OTA service dependencies
+package healthcheck
@@ -0,0 +1,70 @@
+package healthcheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/api/healthcheck"
+)
+
+func TestHTTP(t *testing.T) {
+	t.Run("should probe readiness endpoint of dependency", func(t *testing.T) {
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+		entry := healthcheck.HTTP("svc", srv.URL+"/", nil).Check(context.Background())
+		if entry.Status != api.StatusHealthy {
+			t.Errorf("got %s, expected %s", entry.Status, api.StatusHealthy)
+		}
+		if path != api.ReadinessPath {
+			t.Errorf("got path %s, expected %s", path, api.ReadinessPath)
+		}
+		var details struct {
+			Latency string `json:"latency"`
+		}
+		if b, err := json.Marshal(entry.Data); err != nil || json.Unmarshal(b, &details) != nil || details.Latency == "" {
+			t.Errorf("got details %s, expected latency as duration string", b)
+		}
+	})
+	t.Run("should be unhealthy if dependency is not ready", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		entry := healthcheck.HTTP("svc", srv.URL, nil).Check(context.Background())
+		if entry.Status != api.StatusUnhealthy {
+			t.Errorf("got %s, expected %s", entry.Status, api.StatusUnhealthy)
+		}
+	})
+}
+
+func TestDisk(t *testing.T) {
+	t.Run("should be healthy for writable directory", func(t *testing.T) {
+		entry := healthcheck.Disk("uploads", t.TempDir(), 0).Check(context.Background())
+		if entry.Status != api.StatusHealthy {
+			t.Errorf("got %s (%v), expected %s", entry.Status, entry.Data, api.StatusHealthy)
+		}
+	})
+	t.Run("should be unhealthy for missing directory", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing")
+		entry := healthcheck.Disk("uploads", path, 0).Check(context.Background())
+		if entry.Status != api.StatusUnhealthy {
+			t.Errorf("got %s, expected %s", entry.Status, api.StatusUnhealthy)
+		}
+	})
+	t.Run("should be unhealthy if free space is below limit", func(t *testing.T) {
+		entry := healthcheck.Disk("uploads", t.TempDir(), math.MaxUint64).Check(context.Background())
+		if entry.Status != api.StatusUnhealthy {
+			t.Errorf("got %s, expected %s", entry.Status, api.StatusUnhealthy)
+		}
+	})
+}
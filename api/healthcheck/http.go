@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+// HTTPStatus is HTTP dependency health check details
+type HTTPStatus struct {
+	// URL is probed endpoint
+	URL string `json:"url"`
+	// Latency is request round trip time
+	Latency api.Duration `json:"latency"`
+	// StatusCode is response status code
+	StatusCode int `json:"statusCode,omitempty"`
+	// Error is request error description
+	Error string `json:"error,omitempty"`
+}
+
+// HTTP creates health check of other OTA service probing its api.ReadinessPath,
+// client is http.DefaultClient if nil
+func HTTP(name, baseURL string, client *http.Client) api.HealthCheck {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := strings.TrimSuffix(baseURL, "/") + api.ReadinessPath
+	return api.HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) api.HealthEntryStatus {
+			st := HTTPStatus{URL: url}
+			start := time.Now()
+			code, err := probe(ctx, client, url)
+			st.Latency = api.Duration(time.Since(start))
+			st.StatusCode = code
+			if err != nil {
+				st.Error = err.Error()
+				return api.HealthEntryStatus{Status: api.StatusUnhealthy, Data: st, Resource: url}
+			}
+			return api.HealthEntryStatus{Status: api.StatusHealthy, Data: st, Resource: url}
+		},
+	}
+}
+
+func probe(ctx context.Context, client *http.Client, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,53 @@
+package healthcheck
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// MongoCheckName is default name of MongoDB health check
+const MongoCheckName = "mongodb"
+
+// MongoStatus is MongoDB health check details
+type MongoStatus struct {
+	// Latency is primary ping round trip time
+	Latency api.Duration `json:"latency"`
+	// PrimaryReachable is true if replica set primary responded to ping
+	PrimaryReachable bool `json:"primaryReachable"`
+	// ReplicaSet is replica set state
+	ReplicaSet *mongo.HelloResult `json:"replicaSet,omitempty"`
+	// Pool is connection pool usage
+	Pool mongo.PoolStats `json:"pool"`
+	// Error is ping error description
+	Error string `json:"error,omitempty"`
+}
+
+// Mongo creates health check of MongoDB connection,
+// check is unhealthy if replica set primary is not reachable
+func Mongo(db *mongo.Db) api.HealthCheck {
+	return api.HealthCheck{
+		Name: MongoCheckName,
+		Check: func(ctx context.Context) api.HealthEntryStatus {
+			var st MongoStatus
+			start := time.Now()
+			err := db.Ping(ctx)
+			st.Latency = api.Duration(time.Since(start))
+			st.Pool = db.PoolStats()
+			if err != nil {
+				st.Error = err.Error()
+				return api.HealthEntryStatus{Status: api.StatusUnhealthy, Data: st}
+			}
+			st.PrimaryReachable = true
+			// replica set details are informational, standalone servers have no set name
+			if hello, err := db.Hello(ctx, readpref.Primary()); err == nil {
+				st.ReplicaSet = hello
+			}
+			return api.HealthEntryStatus{Status: api.StatusHealthy, Data: st}
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// HeaderIdempotencyKey is request header with client generated idempotency key
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is response header set on replayed responses
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is default time of keeping stored responses
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLockTimeout is default time of waiting for concurrent request with the same key
+	DefaultIdempotencyLockTimeout = 10 * time.Second
+	// DefaultIdempotencyPollInterval is default interval of checking completion of concurrent request
+	DefaultIdempotencyPollInterval = 100 * time.Millisecond
+	// DefaultIdempotencyProcessingTimeout is default lease time of key held by request in progress
+	DefaultIdempotencyProcessingTimeout = time.Minute
+	// DefaultIdempotencyMaxResponseSize is default maximum size of stored response body
+	DefaultIdempotencyMaxResponseSize = 1 << 20
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyConfig is configuration of Idempotency middleware
+type IdempotencyConfig struct {
+	// TTL is time of keeping stored responses
+	TTL time.Duration
+	// LockTimeout is time of waiting for concurrent request with the same key
+	LockTimeout time.Duration
+	// PollInterval is interval of checking completion of concurrent request
+	PollInterval time.Duration
+	// ProcessingTimeout is lease time of key held by request in progress,
+	// key of request not completed in time (ex. instance crashed) is taken over by its retry
+	ProcessingTimeout time.Duration
+	// Methods are http methods honoring idempotency key
+	Methods []string
+	// MaxBodySize is maximum size of request body
+	MaxBodySize int64
+	// MaxResponseSize is maximum size of stored response body,
+	// larger responses are not stored and key is released
+	MaxResponseSize int64
+	// Required rejects requests without idempotency key
+	Required bool
+	// ReplayHeaders are response headers stored and replayed with response body
+	ReplayHeaders []string
+	// Skipper defines a function to skip middleware
+	Skipper middleware.Skipper
+}
+
+// DefaultIdempotencyConfig returns default Idempotency middleware configuration
+func DefaultIdempotencyConfig() IdempotencyConfig {
+	return IdempotencyConfig{
+		TTL:               DefaultIdempotencyTTL,
+		LockTimeout:       DefaultIdempotencyLockTimeout,
+		PollInterval:      DefaultIdempotencyPollInterval,
+		ProcessingTimeout: DefaultIdempotencyProcessingTimeout,
+		Methods:           []string{http.MethodPost, http.MethodPatch},
+		MaxBodySize:       DefaultMaxBodySize,
+		MaxResponseSize:   DefaultIdempotencyMaxResponseSize,
+		ReplayHeaders:     []string{echo.HeaderContentType, echo.HeaderLocation, HeaderETag},
+		Skipper:           middleware.DefaultSkipper,
+	}
+}
+
+// IdempotencyRecord is stored request fingerprint and its response
+type IdempotencyRecord struct {
+	// Namespace is OTA namespace of request
+	Namespace data.Namespace `bson:"namespace"`
+	// Key is idempotency key
+	Key string `bson:"key"`
+	// Fingerprint is digest of request body
+	Fingerprint string `bson:"fingerprint"`
+	// Method is http method of request
+	Method string `bson:"method"`
+	// Path is URL path of request
+	Path string `bson:"path"`
+	// Completed is false until response is stored
+	Completed bool `bson:"completed"`
+	// StatusCode is http status code of response
+	StatusCode int `bson:"statusCode,omitempty"`
+	// Header is replayed response headers
+	Header http.Header `bson:"header,omitempty"`
+	// Body is response body
+	Body []byte `bson:"body,omitempty"`
+	// CreatedAt is time of first request
+	CreatedAt time.Time `bson:"createdAt"`
+	// ExpiresAt is time of record expiration
+	ExpiresAt time.Time `bson:"expiresAt"`
+	// LockedUntil is time of key lease expiration of not completed record
+	LockedUntil time.Time `bson:"lockedUntil"`
+}
+
+// LeaseExpired reports if record is not completed and its key lease is expired
+func (r *IdempotencyRecord) LeaseExpired(now time.Time) bool {
+	return !r.Completed && !r.LockedUntil.After(now)
+}
+
+// IdempotencyStore is storage of idempotency records
+type IdempotencyStore interface {
+	// Begin stores not completed record if its key is not used or lease of not completed record is expired,
+	// otherwise it returns existing record
+	Begin(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Get returns record by key (nil if record does not exist)
+	Get(ctx context.Context, ns data.Namespace, key string) (*IdempotencyRecord, error)
+	// Complete stores response of record
+	Complete(ctx context.Context, rec *IdempotencyRecord) error
+	// Release removes not completed record allowing to retry request
+	Release(ctx context.Context, ns data.Namespace, key string) error
+}
+
+type idempotencyKey struct {
+	ns  data.Namespace
+	key string
+}
+
+// MemoryIdempotencyStore is in-memory IdempotencyStore (for tests and single instance services)
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore creates new MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[idempotencyKey]IdempotencyRecord)}
+}
+
+// Begin stores not completed record if its key is not used or lease of not completed record is expired,
+// otherwise it returns existing record
+func (s *MemoryIdempotencyStore) Begin(_ context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanup()
+	k := idempotencyKey{ns: rec.Namespace, key: rec.Key}
+	if existing, found := s.records[k]; found && !existing.LeaseExpired(time.Now()) {
+		return &existing, nil
+	}
+	s.records[k] = *rec
+	return nil, nil
+}
+
+// Get returns record by key (nil if record does not exist)
+func (s *MemoryIdempotencyStore) Get(_ context.Context, ns data.Namespace, key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, found := s.records[idempotencyKey{ns: ns, key: key}]
+	if !found || rec.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// Complete stores response of record
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, rec *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[idempotencyKey{ns: rec.Namespace, key: rec.Key}] = *rec
+	return nil
+}
+
+// Release removes not completed record allowing to retry request
+func (s *MemoryIdempotencyStore) Release(_ context.Context, ns data.Namespace, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := idempotencyKey{ns: ns, key: key}
+	if rec, found := s.records[k]; found && !rec.Completed {
+		delete(s.records, k)
+	}
+	return nil
+}
+
+// cleanup removes expired records
+func (s *MemoryIdempotencyStore) cleanup() {
+	now := time.Now()
+	for k, rec := range s.records {
+		if rec.ExpiresAt.Before(now) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/db"
+)
+
+const (
+	// DefaultDrainPeriod is default time between switching readiness off and stopping listeners
+	DefaultDrainPeriod = 5 * time.Second
+	// DefaultShutdownTimeout is default time to wait for in-flight requests on shutdown
+	DefaultShutdownTimeout = 30 * time.Second
+
+	lifecycleCheckName = "lifecycle"
+)
+
+// WarmupHook is function executed on service startup (ex. index creation, cache priming)
+type WarmupHook func(ctx context.Context) error
+
+// Shutdowner is server which can be gracefully shut down (ex. *echo.Echo or *http.Server)
+type Shutdowner interface {
+	// Shutdown stops accepting new connections and waits for in-flight requests
+	Shutdown(ctx context.Context) error
+}
+
+// LifecycleConfig is configuration of service lifecycle controller
+type LifecycleConfig struct {
+	// DrainPeriod is time between switching readiness off and stopping listeners,
+	// it gives load balancers time to remove pod from endpoints
+	// (DefaultDrainPeriod if not set, negative value disables draining)
+	DrainPeriod time.Duration
+	// ShutdownTimeout is time to wait for in-flight requests and db disconnect
+	ShutdownTimeout time.Duration
+	// Signals starting graceful shutdown (SIGTERM and SIGINT if not set)
+	Signals []os.Signal
+}
+
+// Lifecycle controls service startup and graceful shutdown
+type Lifecycle struct {
+	log      logger.Logger
+	cfg      LifecycleConfig
+	started  atomic.Bool
+	draining atomic.Bool
+	drainCh  chan struct{}
+	drain    sync.Once
+	mu       sync.Mutex
+	hooks    []namedWarmupHook
+	repos    []db.BaseRepository
+}
+
+type namedWarmupHook struct {
+	name string
+	hook WarmupHook
+}
+
+// NewLifecycle creates new Lifecycle controller
+func NewLifecycle(lgr logger.Logger, cfg LifecycleConfig) *Lifecycle {
+	if cfg.DrainPeriod < 0 {
+		cfg.DrainPeriod = 0
+	} else if cfg.DrainPeriod == 0 {
+		cfg.DrainPeriod = DefaultDrainPeriod
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+	return &Lifecycle{
+		log:     lgr.SetOperation("Lifecycle"),
+		cfg:     cfg,
+		drainCh: make(chan struct{}),
+	}
+}
+
+// AddWarmupHook registers hook which should complete before startup probe passes
+func (l *Lifecycle) AddWarmupHook(name string, hook WarmupHook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, namedWarmupHook{name: name, hook: hook})
+}
+
+// AddRepository registers repositories disconnected on shutdown
+func (l *Lifecycle) AddRepository(repos ...db.BaseRepository) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.repos = append(l.repos, repos...)
+}
+
+// Warmup runs registered warm-up hooks sequentially and marks service as started
+func (l *Lifecycle) Warmup(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := make([]namedWarmupHook, len(l.hooks))
+	copy(hooks, l.hooks)
+	l.mu.Unlock()
+
+	log := l.log.WithContext(ctx)
+	for _, h := range hooks {
+		start := time.Now()
+		if err := h.hook(ctx); err != nil {
+			return apperrors.CreateErrorAndLogIt(log.WithField("hook", h.name),
+				apperrors.ErrorSvcWarmup,
+				fmt.Sprintf("Warm-up hook %s failed", h.name), err)
+		}
+		log.WithField("hook", h.name).
+			WithField("executionTime", time.Since(start)).
+			Debug("Warm-up hook completed")
+	}
+	l.started.Store(true)
+	log.Info("Service started")
+	return nil
+}
+
+// Started returns true if all warm-up hooks are completed
+func (l *Lifecycle) Started() bool {
+	return l.started.Load()
+}
+
+// Draining returns true if service is shutting down
+func (l *Lifecycle) Draining() bool {
+	return l.draining.Load()
+}
+
+// DrainStarted returns channel closed when service starts draining
+// (long-lived requests like event streams should be completed before servers are stopped)
+func (l *Lifecycle) DrainStarted() <-chan struct{} {
+	return l.drainCh
+}
+
+// ReadinessCheck returns health check failing until service is started and after draining began
+func (l *Lifecycle) ReadinessCheck() HealthCheck {
+	return HealthCheck{
+		Name: lifecycleCheckName,
+		Check: func(context.Context) HealthEntryStatus {
+			switch {
+			case l.Draining():
+				return HealthEntryStatus{Status: StatusUnhealthy, Data: "service is draining"}
+			case !l.Started():
+				return HealthEntryStatus{Status: StatusUnhealthy, Data: "service is starting"}
+			}
+			return HealthEntryStatus{Status: StatusHealthy}
+		},
+	}
+}
+
+// Run blocks until termination signal or ctx cancellation, then gracefully shuts down servers
+func (l *Lifecycle) Run(ctx context.Context, servers ...Shutdowner) error {
+	sigCtx, stop := signal.NotifyContext(ctx, l.cfg.Signals...)
+	defer stop()
+	<-sigCtx.Done()
+	return l.Shutdown(context.Background(), servers...)
+}
+
+// Shutdown switches readiness off, waits drain period, stops servers
+// waiting for in-flight requests and disconnects registered repositories
+func (l *Lifecycle) Shutdown(ctx context.Context, servers ...Shutdowner) error {
+	log := l.log.WithContext(ctx)
+	l.draining.Store(true)
+	l.drain.Do(func() { close(l.drainCh) })
+	log.WithField("drainPeriod", l.cfg.DrainPeriod).
+		Info("Service draining started")
+
+	timer := time.NewTimer(l.cfg.DrainPeriod)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+
+	ctxShutdown, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.cfg.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctxShutdown); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	l.mu.Lock()
+	repos := make([]db.BaseRepository, len(l.repos))
+	copy(repos, l.repos)
+	l.mu.Unlock()
+	for _, repo := range repos {
+		if err := repo.Disconnect(ctxShutdown); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return apperrors.CreateErrorAndLogIt(log,
+			apperrors.ErrorSvcShutdown,
+			"Graceful shutdown failed", errors.Join(errs...))
+	}
+	log.Info("Service stopped")
+	return nil
+}
@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+type fakeServer struct {
+	stopped bool
+}
+
+func (s *fakeServer) Shutdown(context.Context) error {
+	s.stopped = true
+	return nil
+}
+
+type fakeRepository struct {
+	disconnected bool
+}
+
+func (r *fakeRepository) Ping(context.Context) error {
+	return nil
+}
+
+func (r *fakeRepository) Disconnect(context.Context) error {
+	r.disconnected = true
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	newLifecycle := func() *api.Lifecycle {
+		return api.NewLifecycle(logger.NewNopLogger(), api.LifecycleConfig{DrainPeriod: -1})
+	}
+	ready := func(l *api.Lifecycle) bool {
+		return l.ReadinessCheck().Check(context.Background()).Status == api.StatusHealthy
+	}
+
+	t.Run("should start after all warm-up hooks completed", func(t *testing.T) {
+		l := newLifecycle()
+		calls := 0
+		l.AddWarmupHook("indexes", func(context.Context) error {
+			calls++
+			return nil
+		})
+		if l.Started() || ready(l) {
+			t.Error("service should not be started before warm-up")
+		}
+		if err := l.Warmup(context.Background()); err != nil {
+			t.Fatalf("Warmup returned error: %v", err)
+		}
+		if !l.Started() || !ready(l) || calls != 1 {
+			t.Error("service should be started after warm-up")
+		}
+	})
+	t.Run("should not start if warm-up hook failed", func(t *testing.T) {
+		l := newLifecycle()
+		l.AddWarmupHook("cache", func(context.Context) error {
+			return errors.New("failed")
+		})
+		if err := l.Warmup(context.Background()); err == nil {
+			t.Error("Warmup should return error")
+		}
+		if l.Started() {
+			t.Error("service should not be started")
+		}
+	})
+	t.Run("should drain, stop servers and disconnect repositories", func(t *testing.T) {
+		l := newLifecycle()
+		_ = l.Warmup(context.Background())
+		repo := &fakeRepository{}
+		srv := &fakeServer{}
+		l.AddRepository(repo)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := l.Run(ctx, srv); err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+		if ready(l) || !l.Draining() {
+			t.Error("service should not be ready while draining")
+		}
+		if !srv.stopped || !repo.disconnected {
+			t.Error("server should be stopped and repository disconnected")
+		}
+	})
+}
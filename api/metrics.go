@@ -0,0 +1,177 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	// MetricsPath endpoint default path
+	MetricsPath = "/metrics"
+
+	// DefaultMetricsMaxLabelValues is default limit of distinct values of route and namespace labels
+	DefaultMetricsMaxLabelValues = 100
+
+	// metricsOverflowLabel replaces label values above the cardinality limit
+	metricsOverflowLabel = "other"
+	// metricsUnmatchedRoute is route label for requests without registered route
+	metricsUnmatchedRoute = "unmatched"
+)
+
+// MetricsConfig is configuration of http server metrics
+type MetricsConfig struct {
+	// Prefix is prometheus namespace of all metrics (ex. service name)
+	Prefix string
+	// Buckets of request duration histogram in seconds
+	Buckets []float64
+	// SizeBuckets of response size histogram in bytes
+	SizeBuckets []float64
+	// MaxRoutes is limit of distinct values of route label
+	MaxRoutes int
+	// MaxNamespaces is limit of distinct values of namespace label
+	MaxNamespaces int
+	// Skipper defines a function to skip middleware
+	Skipper middleware.Skipper
+}
+
+// DefaultMetricsConfig returns default metrics configuration
+func DefaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		Buckets:       prometheus.DefBuckets,
+		SizeBuckets:   prometheus.ExponentialBuckets(100, 10, 7),
+		MaxRoutes:     DefaultMetricsMaxLabelValues,
+		MaxNamespaces: DefaultMetricsMaxLabelValues,
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == MetricsPath
+		},
+	}
+}
+
+// Metrics is collection of http server prometheus metrics
+type Metrics struct {
+	registry   *prometheus.Registry
+	requests   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	size       *prometheus.HistogramVec
+	inFlight   prometheus.Gauge
+	routes     *labelLimiter
+	namespaces *labelLimiter
+	skipper    middleware.Skipper
+}
+
+// NewMetrics creates http server metrics and registers them in new prometheus registry
+func NewMetrics(cfg MetricsConfig) (*Metrics, error) {
+	def := DefaultMetricsConfig()
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = def.Buckets
+	}
+	if len(cfg.SizeBuckets) == 0 {
+		cfg.SizeBuckets = def.SizeBuckets
+	}
+	if cfg.MaxRoutes <= 0 {
+		cfg.MaxRoutes = def.MaxRoutes
+	}
+	if cfg.MaxNamespaces <= 0 {
+		cfg.MaxNamespaces = def.MaxNamespaces
+	}
+	if cfg.Skipper == nil {
+		cfg.Skipper = def.Skipper
+	}
+	labels := []string{"route", "method", "status", "namespace"}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Prefix,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of processed HTTP requests.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Prefix,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency in seconds.",
+			Buckets:   cfg.Buckets,
+		}, labels),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Prefix,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "HTTP response size in bytes.",
+			Buckets:   cfg.SizeBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cfg.Prefix,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+		routes:     newLabelLimiter(cfg.MaxRoutes),
+		namespaces: newLabelLimiter(cfg.MaxNamespaces),
+		skipper:    cfg.Skipper,
+	}
+
+	err := m.Register(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.size, m.inFlight)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Register adds service specific collectors to metrics registry
+func (m *Metrics) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registry returns prometheus registry used by Metrics
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// labelLimiter caps count of distinct values of metric label
+type labelLimiter struct {
+	mu     sync.RWMutex
+	limit  int
+	values map[string]struct{}
+}
+
+func newLabelLimiter(limit int) *labelLimiter {
+	return &labelLimiter{
+		limit:  limit,
+		values: make(map[string]struct{}, limit),
+	}
+}
+
+// value returns v if it is known or limit is not reached, otherwise metricsOverflowLabel
+func (l *labelLimiter) value(v string) string {
+	l.mu.RLock()
+	_, ok := l.values[v]
+	l.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok = l.values[v]; ok {
+		return v
+	}
+	if len(l.values) >= l.limit {
+		return metricsOverflowLabel
+	}
+	l.values[v] = struct{}{}
+	return v
+}
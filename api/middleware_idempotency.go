@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+// Idempotency middleware replays stored response of request with already used Idempotency-Key header
+// (keys are scoped by namespace), concurrent requests with the same key are serialized
+// and reuse of key with different request is rejected
+func Idempotency(store IdempotencyStore, cfg IdempotencyConfig) echo.MiddlewareFunc {
+	def := DefaultIdempotencyConfig()
+	if cfg.TTL <= 0 {
+		cfg.TTL = def.TTL
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = def.LockTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.ProcessingTimeout <= 0 {
+		cfg.ProcessingTimeout = def.ProcessingTimeout
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = def.Methods
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = def.MaxBodySize
+	}
+	if cfg.MaxResponseSize <= 0 {
+		cfg.MaxResponseSize = def.MaxResponseSize
+	}
+	if cfg.ReplayHeaders == nil {
+		cfg.ReplayHeaders = def.ReplayHeaders
+	}
+	if cfg.Skipper == nil {
+		cfg.Skipper = def.Skipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if cfg.Skipper(c) || !contains(cfg.Methods, req.Method) {
+				return next(c)
+			}
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				if cfg.Required {
+					return apperrors.NewAppError(apperrors.ErrorDataValidation,
+						fmt.Sprintf("%s header is required", HeaderIdempotencyKey))
+				}
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return apperrors.NewAppError(apperrors.ErrorDataValidation,
+					fmt.Sprintf("%s header exceeds %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLength))
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, cfg.MaxBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return errPayloadTooLarge(cfg.MaxBodySize)
+				}
+				return apperrors.CreateError(apperrors.ErrorDataValidation, "failed to read request body", err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			rec := &IdempotencyRecord{
+				Namespace:   GetNamespace(c),
+				Key:         key,
+				Fingerprint: data.ByteDigest(body),
+				Method:      req.Method,
+				Path:        req.URL.Path,
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(cfg.TTL),
+				LockedUntil: now.Add(cfg.ProcessingTimeout),
+			}
+			ctx := GetRequestContext(c)
+			existing, err := acquireIdempotencyKey(ctx, store, rec, cfg)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return replayResponse(c, existing)
+			}
+			return processIdempotent(c, next, store, rec, cfg)
+		}
+	}
+}
+
+// acquireIdempotencyKey reserves key of record, it returns completed record if key was already used
+func acquireIdempotencyKey(ctx context.Context, store IdempotencyStore, rec *IdempotencyRecord, cfg IdempotencyConfig) (*IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.LockTimeout)
+	defer cancel()
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		existing, err := store.Begin(ctx, rec)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		for existing != nil && !existing.Completed {
+			if !sameIdempotentRequest(existing, rec) || existing.LeaseExpired(time.Now()) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, apperrors.NewAppError(apperrors.ErrorSvcIdempotencyInProgress,
+					"request with the same idempotency key is in progress")
+			case <-ticker.C:
+			}
+			if existing, err = store.Get(ctx, rec.Namespace, rec.Key); err != nil {
+				return nil, err
+			}
+		}
+		if existing == nil || existing.LeaseExpired(time.Now()) {
+			// concurrent request failed and released key or did not complete in time
+			continue
+		}
+		if !sameIdempotentRequest(existing, rec) {
+			return nil, apperrors.NewAppError(apperrors.ErrorSvcIdempotencyKeyReused,
+				"idempotency key was already used with different request")
+		}
+		return existing, nil
+	}
+}
+
+// processIdempotent calls handler and stores its response,
+// key is released if handler failed with server error, panicked, response was too large or not stored to allow retry
+func processIdempotent(c echo.Context, next echo.HandlerFunc, store IdempotencyStore, rec *IdempotencyRecord, cfg IdempotencyConfig) error {
+	resp := c.Response()
+	recorder := &bodyRecorder{ResponseWriter: resp.Writer, limit: cfg.MaxResponseSize}
+	resp.Writer = recorder
+	defer func() { resp.Writer = recorder.ResponseWriter }()
+
+	ctx := context.WithoutCancel(GetRequestContext(c))
+	release := func() {
+		if err := store.Release(ctx, rec.Namespace, rec.Key); err != nil {
+			c.Logger().Errorf("Failed to release idempotency key. Error: %v", err)
+		}
+	}
+	defer func() {
+		// panic is handled by Recover middleware, key is released before
+		if r := recover(); r != nil {
+			release()
+			panic(r)
+		}
+	}()
+
+	if err := next(c); err != nil {
+		// render error response to store it, rendered error is not returned to not render it again
+		c.Error(err)
+	}
+
+	if resp.Status >= http.StatusInternalServerError {
+		release()
+		return nil
+	}
+	if recorder.overflow {
+		c.Logger().Warnf("Idempotent response exceeds %d bytes and is not stored", cfg.MaxResponseSize)
+		release()
+		return nil
+	}
+
+	rec.Completed = true
+	rec.StatusCode = resp.Status
+	rec.Body = recorder.body.Bytes()
+	rec.Header = make(http.Header)
+	for _, h := range cfg.ReplayHeaders {
+		if v := resp.Header().Values(h); len(v) > 0 {
+			rec.Header[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+	if err := store.Complete(ctx, rec); err != nil {
+		c.Logger().Errorf("Failed to store idempotent response. Error: %v", err)
+		release()
+	}
+	return nil
+}
+
+// replayResponse sends stored response
+func replayResponse(c echo.Context, rec *IdempotencyRecord) error {
+	h := c.Response().Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set(HeaderIdempotentReplayed, "true")
+	c.Response().WriteHeader(rec.StatusCode)
+	_, err := c.Response().Write(rec.Body)
+	return err
+}
+
+// sameIdempotentRequest reports if requests have the same fingerprint, method and path
+func sameIdempotentRequest(a, b *IdempotencyRecord) bool {
+	return a.Fingerprint == b.Fingerprint && a.Method == b.Method && a.Path == b.Path
+}
+
+// bodyRecorder copies written response body up to limit
+type bodyRecorder struct {
+	http.ResponseWriter
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if !r.overflow {
+		if int64(r.body.Len()+len(b)) > r.limit {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
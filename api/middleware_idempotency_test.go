@@ -0,0 +1,211 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+func TestIdempotency(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		failing atomic.Bool
+		panics  atomic.Bool
+		release = make(chan struct{})
+		blocked atomic.Bool
+		store   = &unreliableIdempotencyStore{MemoryIdempotencyStore: api.NewMemoryIdempotencyStore()}
+	)
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	cfg := api.DefaultIdempotencyConfig()
+	cfg.PollInterval = 5 * time.Millisecond
+	cfg.MaxResponseSize = 64
+	e.Use(middleware.Recover(), api.Idempotency(store, cfg))
+	e.POST("/campaigns", func(c echo.Context) error {
+		n := calls.Add(1)
+		if blocked.Load() {
+			<-release
+		}
+		if panics.Load() {
+			panic("handler failed")
+		}
+		if failing.Load() {
+			return apperrors.NewAppError(apperrors.ErrorDbOperation, "db is down")
+		}
+		c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/campaigns/%d", n))
+		return c.JSON(http.StatusCreated, map[string]int32{"id": n})
+	})
+	e.POST("/reports", func(c echo.Context) error {
+		calls.Add(1)
+		return c.String(http.StatusOK, strings.Repeat("x", 100))
+	})
+	postTo := func(path, key, ns, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(api.HeaderIdempotencyKey, key)
+		req.Header.Set("x-ats-namespace", ns)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	post := func(key, ns, body string) *httptest.ResponseRecorder {
+		return postTo("/campaigns", key, ns, body)
+	}
+
+	t.Run("should replay stored response", func(t *testing.T) {
+		calls.Store(0)
+		first := post("key-1", "ns1", `{"name":"a"}`)
+		second := post("key-1", "ns1", `{"name":"a"}`)
+		if calls.Load() != 1 {
+			t.Errorf("handler called %d times, expected once", calls.Load())
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("got %d %s, expected %d %s", second.Code, second.Body, first.Code, first.Body)
+		}
+		if second.Header().Get(echo.HeaderLocation) != "/campaigns/1" || second.Header().Get(api.HeaderIdempotentReplayed) != "true" {
+			t.Errorf("got headers %v", second.Header())
+		}
+	})
+	t.Run("should scope keys by namespace", func(t *testing.T) {
+		calls.Store(0)
+		post("key-2", "ns1", `{}`)
+		post("key-2", "ns2", `{}`)
+		if calls.Load() != 2 {
+			t.Errorf("handler called %d times, expected twice", calls.Load())
+		}
+	})
+	t.Run("should reject reuse of key with different body", func(t *testing.T) {
+		post("key-3", "ns1", `{"name":"a"}`)
+		rec := post("key-3", "ns1", `{"name":"b"}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusUnprocessableEntity)
+		}
+	})
+	t.Run("should release key if request failed", func(t *testing.T) {
+		calls.Store(0)
+		failing.Store(true)
+		if rec := post("key-4", "ns1", `{}`); rec.Code != http.StatusInternalServerError {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusInternalServerError)
+		}
+		failing.Store(false)
+		if rec := post("key-4", "ns1", `{}`); rec.Code != http.StatusCreated {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusCreated)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("handler called %d times, expected retry", calls.Load())
+		}
+	})
+	t.Run("should release key if handler panicked", func(t *testing.T) {
+		calls.Store(0)
+		panics.Store(true)
+		if rec := post("key-panic", "ns1", `{}`); rec.Code != http.StatusInternalServerError {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusInternalServerError)
+		}
+		panics.Store(false)
+		if rec := post("key-panic", "ns1", `{}`); rec.Code != http.StatusCreated {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusCreated)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("handler called %d times, expected retry", calls.Load())
+		}
+	})
+	t.Run("should release key if response was not stored", func(t *testing.T) {
+		calls.Store(0)
+		store.failComplete.Store(true)
+		if rec := post("key-store", "ns1", `{}`); rec.Code != http.StatusCreated {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusCreated)
+		}
+		store.failComplete.Store(false)
+		if rec := post("key-store", "ns1", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(api.HeaderIdempotentReplayed) != "" {
+			t.Errorf("got status %d, expected processed request", rec.Code)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("handler called %d times, expected retry", calls.Load())
+		}
+	})
+	t.Run("should not store response exceeding limit", func(t *testing.T) {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			rec := postTo("/reports", "key-large", "ns1", `{}`)
+			if rec.Code != http.StatusOK || rec.Body.Len() != 100 || rec.Header().Get(api.HeaderIdempotentReplayed) != "" {
+				t.Errorf("got %d with %d bytes, expected processed response", rec.Code, rec.Body.Len())
+			}
+		}
+		if calls.Load() != 2 {
+			t.Errorf("handler called %d times, expected twice", calls.Load())
+		}
+	})
+	t.Run("should take over key of request not completed in time", func(t *testing.T) {
+		calls.Store(0)
+		now := time.Now()
+		crashed := &api.IdempotencyRecord{
+			Namespace:   data.NewNamespace("ns1"),
+			Key:         "key-crashed",
+			Fingerprint: data.ByteDigest([]byte(`{}`)),
+			Method:      http.MethodPost,
+			Path:        "/campaigns",
+			CreatedAt:   now.Add(-time.Hour),
+			ExpiresAt:   now.Add(time.Hour),
+			LockedUntil: now.Add(-time.Second),
+		}
+		if _, err := store.Begin(context.Background(), crashed); err != nil {
+			t.Fatal(err)
+		}
+		if rec := post("key-crashed", "ns1", `{}`); rec.Code != http.StatusCreated {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusCreated)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("handler called %d times, expected once", calls.Load())
+		}
+	})
+	t.Run("should serialize concurrent duplicates", func(t *testing.T) {
+		calls.Store(0)
+		blocked.Store(true)
+		var wg sync.WaitGroup
+		results := make([]*httptest.ResponseRecorder, 3)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = post("key-5", "ns1", `{}`)
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		blocked.Store(false)
+		close(release)
+		wg.Wait()
+		if calls.Load() != 1 {
+			t.Errorf("handler called %d times, expected once", calls.Load())
+		}
+		for _, rec := range results {
+			if rec.Code != http.StatusCreated || rec.Body.String() != results[0].Body.String() {
+				t.Errorf("got %d %s, expected the same response", rec.Code, rec.Body)
+			}
+		}
+	})
+}
+
+// unreliableIdempotencyStore fails to store responses on demand
+type unreliableIdempotencyStore struct {
+	*api.MemoryIdempotencyStore
+	failComplete atomic.Bool
+}
+
+func (s *unreliableIdempotencyStore) Complete(ctx context.Context, rec *api.IdempotencyRecord) error {
+	if s.failComplete.Load() {
+		return apperrors.NewAppError(apperrors.ErrorDbOperation, "db is down")
+	}
+	return s.MemoryIdempotencyStore.Complete(ctx, rec)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Middleware returns echo middleware collecting http server metrics
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m.skipper(c) {
+				return next(c)
+			}
+
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+			start := time.Now()
+
+			if err := next(c); err != nil {
+				// render error response to get actual status code and response size,
+				// rendered error is not returned to not render it again
+				c.Error(err)
+			}
+
+			labels := prometheus.Labels{
+				"route":     m.routes.value(routeLabel(c)),
+				"method":    c.Request().Method,
+				"status":    statusClass(c.Response().Status),
+				"namespace": m.namespaces.value(string(GetNamespace(c))),
+			}
+			m.requests.With(labels).Inc()
+			m.duration.With(labels).Observe(time.Since(start).Seconds())
+			m.size.With(labels).Observe(float64(c.Response().Size))
+
+			return nil
+		}
+	}
+}
+
+// routeLabel returns route template of request
+func routeLabel(c echo.Context) string {
+	path := c.Path()
+	if path == "" {
+		return metricsUnmatchedRoute
+	}
+	return path
+}
+
+// statusClass converts http status code to its class (ex. 2xx)
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return strconv.Itoa(code)
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
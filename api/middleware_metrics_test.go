@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestMetrics(t *testing.T) {
+	newServer := func(t *testing.T, cfg api.MetricsConfig) *echo.Echo {
+		m, err := api.NewMetrics(cfg)
+		if err != nil {
+			t.Fatalf("NewMetrics returned error: %v", err)
+		}
+		e := echo.New()
+		e.HTTPErrorHandler = api.NewErrorHandler().Handler
+		e.Use(m.Middleware())
+		e.GET(api.MetricsPath, api.MetricsHandler(m))
+		e.GET("/items/:id", func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		})
+		e.GET("/fail", func(c echo.Context) error {
+			return echo.NewHTTPError(http.StatusNotFound, "not found")
+		})
+		return e
+	}
+	scrape := func(e *echo.Echo) string {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.MetricsPath, nil))
+		return rec.Body.String()
+	}
+	request := func(e *echo.Echo, path, ns string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ns != "" {
+			req.Header.Set("x-ats-namespace", ns)
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("should label requests with route template and status class", func(t *testing.T) {
+		e := newServer(t, api.DefaultMetricsConfig())
+		request(e, "/items/1", "")
+		request(e, "/items/2", "")
+		request(e, "/fail", "")
+		body := scrape(e)
+		expected := []string{
+			`http_requests_total{method="GET",namespace="default",route="/items/:id",status="2xx"} 2`,
+			`http_requests_total{method="GET",namespace="default",route="/fail",status="4xx"} 1`,
+		}
+		for _, line := range expected {
+			if !strings.Contains(body, line) {
+				t.Errorf("metrics output does not contain %s", line)
+			}
+		}
+		if strings.Contains(body, `route="/metrics"`) {
+			t.Error("metrics endpoint should be skipped")
+		}
+	})
+	t.Run("should cap namespace label cardinality", func(t *testing.T) {
+		cfg := api.DefaultMetricsConfig()
+		cfg.MaxNamespaces = 1
+		e := newServer(t, cfg)
+		request(e, "/items/1", "ns1")
+		request(e, "/items/1", "ns2")
+		request(e, "/items/1", "ns3")
+		body := scrape(e)
+		if !strings.Contains(body, `namespace="ns1"`) {
+			t.Error("first namespace should be kept")
+		}
+		if strings.Contains(body, `namespace="ns2"`) {
+			t.Error("namespace above limit should be replaced")
+		}
+		if !strings.Contains(body, `http_requests_total{method="GET",namespace="other",route="/items/:id",status="2xx"} 2`) {
+			t.Error("namespaces above limit should be reported as other")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// HeaderRateLimitLimit is response header with request quota
+	HeaderRateLimitLimit = "RateLimit-Limit"
+	// HeaderRateLimitRemaining is response header with remaining request quota
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	// HeaderRateLimitReset is response header with number of seconds until quota reset
+	HeaderRateLimitReset = "RateLimit-Reset"
+	// HeaderRateLimitPolicy is response header with quota policy (ex. "100;w=60")
+	HeaderRateLimitPolicy = "RateLimit-Policy"
+	// HeaderRetryAfter is response header with number of seconds until next request is allowed
+	HeaderRetryAfter = "Retry-After"
+)
+
+// RateLimitConfig is configuration of RateLimiting middleware
+type RateLimitConfig struct {
+	// Algorithm is rate limiting algorithm
+	Algorithm RateLimitAlgorithm
+	// Limit is default rate limit of namespace
+	Limit RateLimit
+	// Routes overrides rate limit of routes (key is "METHOD /route/:template"),
+	// limits of routes are counted separately
+	Routes map[string]RateLimit
+	// PerRoute counts limits of all routes separately
+	PerRoute bool
+	// APIKeyHeader is request header with API key, requests with API key are limited by APIKeyLimit
+	// in addition to limit of namespace (rotating API keys does not bypass limit of namespace)
+	APIKeyHeader string
+	// APIKeyLimit is rate limit of each API key (limit of route or namespace is used if it is not set)
+	APIKeyLimit RateLimit
+	// Skipper defines a function to skip middleware
+	Skipper middleware.Skipper
+}
+
+// RateLimiting middleware limits request rate per namespace (optionally per route and additionally per API key)
+// and sends RateLimit-* headers, requests above limit are rejected with ErrorSvcRateLimited error
+// (requests are allowed if rate limit store is unavailable)
+func RateLimiting(store RateLimitStore, cfg RateLimitConfig) echo.MiddlewareFunc {
+	if cfg.Skipper == nil {
+		cfg.Skipper = middleware.DefaultSkipper
+	}
+	limiter := NewRateLimiter(store, cfg.Algorithm)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper(c) {
+				return next(c)
+			}
+			route := fmt.Sprintf("%s %s", c.Request().Method, c.Path())
+			limit, perRoute := cfg.Routes[route]
+			if !perRoute {
+				limit = cfg.Limit
+			}
+			if limit.Limit <= 0 || limit.Window <= 0 {
+				return next(c)
+			}
+
+			key := rateLimitKey(c, route, perRoute || cfg.PerRoute)
+			checks := make([]rateLimitCheck, 0, 2)
+			if apiKey := rateLimitAPIKey(c, cfg); apiKey != "" {
+				keyLimit := cfg.APIKeyLimit
+				if keyLimit.Limit <= 0 || keyLimit.Window <= 0 {
+					keyLimit = limit
+				}
+				// do not store API keys as plain text
+				checks = append(checks, rateLimitCheck{key: key + "|key:" + data.Digest(apiKey), limit: keyLimit})
+			}
+			checks = append(checks, rateLimitCheck{key: key, limit: limit})
+
+			ctx, now := GetRequestContext(c), time.Now()
+			var res RateLimitResult
+			for i, check := range checks {
+				r, err := limiter.Allow(ctx, check.key, check.limit, now)
+				if err != nil {
+					c.Logger().Errorf("Failed to check rate limit. Error: %v", err)
+					return next(c)
+				}
+				// report the most restrictive limit
+				if i == 0 || !r.Allowed || r.Remaining < res.Remaining {
+					res, limit = r, check.limit
+				}
+				if !r.Allowed {
+					break
+				}
+			}
+
+			h := c.Response().Header()
+			h.Set(HeaderRateLimitLimit, strconv.FormatInt(res.Limit, 10))
+			h.Set(HeaderRateLimitRemaining, strconv.FormatInt(res.Remaining, 10))
+			h.Set(HeaderRateLimitReset, strconv.FormatInt(durationSeconds(res.Reset), 10))
+			h.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%d", limit.Limit, durationSeconds(limit.Window)))
+			if !res.Allowed {
+				h.Set(HeaderRetryAfter, strconv.FormatInt(durationSeconds(res.RetryAfter), 10))
+				return apperrors.NewAppError(apperrors.ErrorSvcRateLimited,
+					fmt.Sprintf("rate limit exceeded, retry after %s", res.RetryAfter.Round(time.Second)))
+			}
+			return next(c)
+		}
+	}
+}
+
+// rateLimitCheck is rate limit checked for request
+type rateLimitCheck struct {
+	key   string
+	limit RateLimit
+}
+
+// rateLimitKey returns key of rate limiter state of namespace (and route)
+func rateLimitKey(c echo.Context, route string, perRoute bool) string {
+	key := "ns:" + string(GetNamespace(c))
+	if perRoute {
+		key += "|route:" + route
+	}
+	return key
+}
+
+// rateLimitAPIKey returns API key of request (empty if it is not configured or not provided)
+func rateLimitAPIKey(c echo.Context, cfg RateLimitConfig) string {
+	if cfg.APIKeyHeader == "" {
+		return ""
+	}
+	return c.Request().Header.Get(cfg.APIKeyHeader)
+}
+
+// durationSeconds rounds duration up to seconds
+func durationSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := api.RateLimit{Limit: 10, Window: time.Minute}
+
+	t.Run("token bucket should allow burst and refill with constant rate", func(t *testing.T) {
+		l := api.NewRateLimiter(api.NewMemoryRateLimitStore(), api.TokenBucket)
+		for i := 0; i < 10; i++ {
+			if res, _ := l.Allow(ctx, "ns", limit, start); !res.Allowed || res.Remaining != int64(9-i) {
+				t.Fatalf("request %d: got %+v, expected allowed", i, res)
+			}
+		}
+		res, _ := l.Allow(ctx, "ns", limit, start)
+		if res.Allowed || res.RetryAfter != 6*time.Second {
+			t.Errorf("got %+v, expected rejection with retry after 6s", res)
+		}
+		if res, _ = l.Allow(ctx, "ns", limit, start.Add(6*time.Second)); !res.Allowed {
+			t.Errorf("got %+v, expected refilled token", res)
+		}
+	})
+	t.Run("sliding window should weight previous window", func(t *testing.T) {
+		l := api.NewRateLimiter(api.NewMemoryRateLimitStore(), api.SlidingWindow)
+		for i := 0; i < 10; i++ {
+			if res, _ := l.Allow(ctx, "ns", limit, start.Add(30*time.Second)); !res.Allowed {
+				t.Fatalf("request %d: got %+v, expected allowed", i, res)
+			}
+		}
+		if res, _ := l.Allow(ctx, "ns", limit, start.Add(59*time.Second)); res.Allowed || res.RetryAfter != time.Second {
+			t.Errorf("got %+v, expected rejection until end of window", res)
+		}
+		// 15s into next window previous window weight is 0.75 (7.5 requests)
+		now := start.Add(75 * time.Second)
+		for i := 0; i < 2; i++ {
+			if res, _ := l.Allow(ctx, "ns", limit, now); !res.Allowed {
+				t.Fatalf("request %d: got %+v, expected allowed", i, res)
+			}
+		}
+		res, _ := l.Allow(ctx, "ns", limit, now)
+		if res.Allowed || res.RetryAfter != 3*time.Second {
+			t.Errorf("got %+v, expected rejection with retry after 3s", res)
+		}
+	})
+	t.Run("should not allow concurrent requests above limit", func(t *testing.T) {
+		l := api.NewRateLimiter(api.NewMemoryRateLimitStore(), api.SlidingWindow)
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res, err := l.Allow(ctx, "ns", limit, start); err == nil && res.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := allowed.Load(); n != 10 {
+			t.Errorf("got %d allowed requests, expected 10", n)
+		}
+	})
+	t.Run("should keep keys separately", func(t *testing.T) {
+		l := api.NewRateLimiter(api.NewMemoryRateLimitStore(), api.TokenBucket)
+		one := api.RateLimit{Limit: 1, Window: time.Minute}
+		l.Allow(ctx, "ns1", one, start)
+		if res, _ := l.Allow(ctx, "ns2", one, start); !res.Allowed {
+			t.Errorf("got %+v, expected allowed", res)
+		}
+	})
+}
+
+func TestRateLimiting(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.Use(api.RateLimiting(api.NewMemoryRateLimitStore(), api.RateLimitConfig{
+		Limit: api.RateLimit{Limit: 2, Window: time.Hour},
+		Routes: map[string]api.RateLimit{
+			"POST /uploads": {Limit: 1, Window: time.Hour},
+		},
+		APIKeyHeader: "X-Api-Key",
+		APIKeyLimit:  api.RateLimit{Limit: 1, Window: time.Hour},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/devices", ok)
+	e.POST("/uploads", ok)
+	request := func(method, ns, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/devices", nil)
+		if method == http.MethodPost {
+			req = httptest.NewRequest(method, "/uploads", nil)
+		}
+		req.Header.Set("x-ats-namespace", ns)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should reject requests above namespace limit", func(t *testing.T) {
+		rec := request(http.MethodGet, "ns1", "")
+		if rec.Header().Get(api.HeaderRateLimitLimit) != "2" || rec.Header().Get(api.HeaderRateLimitRemaining) != "1" {
+			t.Errorf("got headers %v", rec.Header())
+		}
+		request(http.MethodGet, "ns1", "")
+		rec = request(http.MethodGet, "ns1", "")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got status %d, expected %d", rec.Code, http.StatusTooManyRequests)
+		}
+		if rec.Header().Get(api.HeaderRetryAfter) != "1800" || rec.Header().Get(api.HeaderRateLimitPolicy) != "2;w=3600" {
+			t.Errorf("got headers %v", rec.Header())
+		}
+		if rec = request(http.MethodGet, "ns2", ""); rec.Code != http.StatusOK {
+			t.Errorf("other namespace: got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+	})
+	t.Run("should count route limits separately", func(t *testing.T) {
+		if rec := request(http.MethodPost, "ns1", ""); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected route limit to be separate", rec.Code)
+		}
+		if rec := request(http.MethodPost, "ns1", ""); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusTooManyRequests)
+		}
+	})
+	t.Run("should limit API key in addition to namespace", func(t *testing.T) {
+		if rec := request(http.MethodGet, "ns3", "key1"); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+		rec := request(http.MethodGet, "ns3", "key1")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get(api.HeaderRateLimitPolicy) != "1;w=3600" {
+			t.Errorf("got status %d and headers %v, expected API key limit", rec.Code, rec.Header())
+		}
+		if rec = request(http.MethodGet, "ns3", "key2"); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+		if rec = request(http.MethodGet, "ns3", "key3"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("got status %d, expected namespace limit to apply to rotated API keys", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// RequestID middleware ensures request has `X-Request-ID` header
+// (generated from trace id if missing) and copies it to the response
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rid := GetRequestID(c)
+			c.Request().Header.Set(echo.HeaderXRequestID, rid)
+			c.Response().Header().Set(echo.HeaderXRequestID, rid)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// DefaultRequestTimeout is default deadline of request handling
+const DefaultRequestTimeout = 30 * time.Second
+
+// TimeoutConfig is configuration of Timeout middleware
+type TimeoutConfig struct {
+	// Timeout is default deadline of request handling
+	Timeout time.Duration
+	// Routes overrides deadline of routes (key is "METHOD /route/:template"),
+	// zero or negative value disables deadline of route (ex. long-running uploads)
+	Routes map[string]time.Duration
+	// Skipper defines a function to skip middleware (server-sent events streams by default,
+	// long-lived streams would be canceled on deadline)
+	Skipper middleware.Skipper
+}
+
+// DefaultTimeoutConfig returns default configuration of Timeout middleware
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		Timeout: DefaultRequestTimeout,
+		Skipper: EventStreamSkipper,
+	}
+}
+
+// Timeout middleware cancels request context on deadline of route.
+// If handler overruns deadline ErrorSvcTimeout error is sent immediately and later writes of handler are ignored,
+// ErrorDbTimeout error of handler (db operation deadline fired before request deadline) is passed as is.
+// Middleware waits for handler to return before request is completed, so handlers should respect context cancellation.
+func Timeout(cfg TimeoutConfig) echo.MiddlewareFunc {
+	def := DefaultTimeoutConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.Skipper == nil {
+		cfg.Skipper = def.Skipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper(c) {
+				return next(c)
+			}
+			timeout, found := cfg.Routes[fmt.Sprintf("%s %s", c.Request().Method, c.Path())]
+			if !found {
+				timeout = cfg.Timeout
+			}
+			if timeout <= 0 {
+				return next(c)
+			}
+			return handleWithTimeout(c, next, timeout)
+		}
+	}
+}
+
+// handleWithTimeout runs handler with request deadline, on deadline timeout error response is sent
+func handleWithTimeout(c echo.Context, next echo.HandlerFunc, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	defer cancel()
+	req := c.Request().WithContext(ctx)
+	c.SetRequest(req)
+	res := c.Response()
+	original := res.Writer
+	tw := newTimeoutWriter(original)
+	res.Writer = tw
+
+	done := make(chan handlerResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- handlerResult{panic: r}
+			}
+		}()
+		done <- handlerResult{err: next(c)}
+	}()
+
+	var r handlerResult
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// client has gone, handler should return soon
+			r = <-done
+			break
+		}
+		status, size, sent := tw.timeout(c.Echo(), req, timeoutError(timeout))
+		// echo context must not be released while handler is running
+		r = <-done
+		res.Writer = original
+		if r.panic != nil {
+			c.Logger().Errorf("Handler panicked after request deadline %s exceeded: %v", timeout, r.panic)
+		}
+		if !sent {
+			c.Logger().Errorf("Request deadline %s exceeded after response was started", timeout)
+			return nil
+		}
+		res.Status, res.Size, res.Committed = status, size, true
+		return nil
+	}
+	tw.restore()
+	res.Writer = original
+	if r.panic != nil {
+		panic(r.panic)
+	}
+	if r.err != nil && !res.Committed && errors.Is(ctx.Err(), context.DeadlineExceeded) &&
+		apperrors.ToAppError(r.err).ErrorCode != apperrors.ErrorDbTimeout {
+		// handler returned error caused by request deadline
+		return timeoutError(timeout)
+	}
+	return r.err
+}
+
+// handlerResult is result of handler running in separate goroutine
+type handlerResult struct {
+	err   error
+	panic interface{}
+}
+
+// timeoutError returns error of request overran its deadline
+func timeoutError(timeout time.Duration) error {
+	return apperrors.NewAppError(apperrors.ErrorSvcTimeout,
+		fmt.Sprintf("request was not handled in %s", timeout))
+}
+
+// timeoutWriter is http.ResponseWriter ignoring writes after request deadline,
+// headers of handler are kept separately from headers of timeout response
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func newTimeoutWriter(w http.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{w: w, header: w.Header().Clone()}
+}
+
+// Header returns response headers of handler
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader sends response headers if request deadline is not exceeded
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.copyHeader()
+	tw.w.WriteHeader(code)
+}
+
+// restore copies headers of handler to underlying writer if response was not started
+// (ex. handler set headers and returned error)
+func (tw *timeoutWriter) restore() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut && !tw.wroteHeader {
+		tw.copyHeader()
+	}
+}
+
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+// Write sends response body, it returns http.ErrHandlerTimeout if request deadline is exceeded
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush sends buffered data to client if request deadline is not exceeded
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// timeout marks writer as timed out and sends error response rendered by echo error handler
+// (if handler has not started response yet), it returns status and size of sent response
+func (tw *timeoutWriter) timeout(e *echo.Echo, req *http.Request, err error) (int, int64, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if tw.wroteHeader {
+		return 0, 0, false
+	}
+	// response is rendered to buffer to set Content-Length,
+	// so client receives complete response while handler is still running
+	buf := &bufferedResponse{header: tw.w.Header()}
+	e.HTTPErrorHandler(err, e.NewContext(req, buf))
+	if buf.status == 0 {
+		buf.status = http.StatusGatewayTimeout
+	}
+	buf.header.Set(echo.HeaderContentLength, strconv.Itoa(buf.body.Len()))
+	tw.w.WriteHeader(buf.status)
+	n, _ := tw.w.Write(buf.body.Bytes())
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return buf.status, int64(n), true
+}
+
+// bufferedResponse is http.ResponseWriter keeping response in memory
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
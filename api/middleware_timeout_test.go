@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestTimeout(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.Use(api.Timeout(api.TimeoutConfig{
+		Timeout: 20 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"GET /stream": 0,
+		},
+	}))
+	lateWrite := make(chan error, 1)
+	e.GET("/slow", func(c echo.Context) error {
+		// handler ignoring context cancellation
+		time.Sleep(50 * time.Millisecond)
+		lateWrite <- c.String(http.StatusOK, "late")
+		return nil
+	})
+	e.GET("/cancelled", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	e.GET("/db", func(c echo.Context) error {
+		return apperrors.NewAppError(apperrors.ErrorDbTimeout, "query timeout")
+	})
+	e.GET("/stream", func(c echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/events", func(c echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/header", func(c echo.Context) error {
+		c.Response().Header().Set(api.HeaderRetryAfter, "5")
+		return apperrors.NewAppError(apperrors.ErrorSvcOverloaded, "overloaded")
+	})
+	serve := func(path string) (*httptest.ResponseRecorder, api.ErrorResponse) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var res api.ErrorResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec, res
+	}
+
+	t.Run("should send timeout error and ignore late writes", func(t *testing.T) {
+		rec, res := serve("/slow")
+		if rec.Code != http.StatusGatewayTimeout || res.ErrorCode != apperrors.ErrorSvcTimeout {
+			t.Errorf("got status %d error %q, expected %d %q", rec.Code, res.ErrorCode, http.StatusGatewayTimeout, apperrors.ErrorSvcTimeout)
+		}
+		if err := <-lateWrite; err == nil {
+			t.Error("expected late write to fail")
+		}
+	})
+	t.Run("should send timeout error if handler returned context error", func(t *testing.T) {
+		if rec, res := serve("/cancelled"); rec.Code != http.StatusGatewayTimeout || res.ErrorCode != apperrors.ErrorSvcTimeout {
+			t.Errorf("got status %d error %q, expected %q", rec.Code, res.ErrorCode, apperrors.ErrorSvcTimeout)
+		}
+	})
+	t.Run("should keep dependency timeout error", func(t *testing.T) {
+		if rec, res := serve("/db"); rec.Code != http.StatusGatewayTimeout || res.ErrorCode != apperrors.ErrorDbTimeout {
+			t.Errorf("got status %d error %q, expected %q", rec.Code, res.ErrorCode, apperrors.ErrorDbTimeout)
+		}
+	})
+	t.Run("should disable deadline of route", func(t *testing.T) {
+		if rec, _ := serve("/stream"); rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+	})
+	t.Run("should skip event streams", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set(echo.HeaderAccept, api.MIMETextEventStream)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusOK)
+		}
+	})
+	t.Run("should keep headers of handler error", func(t *testing.T) {
+		if rec, _ := serve("/header"); rec.Code != http.StatusServiceUnavailable || rec.Header().Get(api.HeaderRetryAfter) != "5" {
+			t.Errorf("got status %d headers %v", rec.Code, rec.Header())
+		}
+	})
+}
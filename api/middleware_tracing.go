@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shuvava/go-ota-svc-common/tracing"
+)
+
+// Tracing middleware starts server span for each request,
+// continuing distributed trace from incoming W3C traceparent header
+func Tracing() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().
+				Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			route := routeLabel(c)
+			ctx, span := tracing.Tracer().Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPRoute(route),
+					tracing.AttributeNamespace.String(string(GetNamespace(c))),
+				))
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+			span.SetAttributes(tracing.AttributeRequestID.String(GetRequestID(c)))
+
+			if err := next(c); err != nil {
+				// render error response to get actual status code (error is recorded on span by ErrorHandler),
+				// rendered error is not returned to not render it again
+				c.Error(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	const (
+		traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+		traceparent = "00-" + traceID + "-00f067aa0ba902b7-01"
+	)
+	tp, exp := tracing.NewInMemoryProvider(tracing.Config{ServiceName: "test"})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	var rendered int
+	handler := api.NewErrorHandler().Handler
+	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		rendered++
+		handler(err, c)
+	}
+	e.Use(api.Tracing())
+	e.GET("/items/:id", func(c echo.Context) error {
+		ctx := api.GetRequestContext(c)
+		return c.JSON(http.StatusInternalServerError, api.NewErrorResponse(ctx, http.StatusInternalServerError, echo.ErrInternalServerError))
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "item not found")
+	})
+
+	t.Run("should continue trace from traceparent header", func(t *testing.T) {
+		exp.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+		req.Header.Set("traceparent", traceparent)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		spans := exp.GetSpans()
+		if len(spans) != 1 {
+			t.Fatalf("got %d spans, expected 1", len(spans))
+		}
+		if got := spans[0].SpanContext.TraceID().String(); got != traceID {
+			t.Errorf("got trace id %s, expected %s", got, traceID)
+		}
+		if spans[0].Name != "GET /items/:id" {
+			t.Errorf("got span name %s, expected route template", spans[0].Name)
+		}
+
+		var resp api.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal returned error: %v", err)
+		}
+		if resp.TraceID != traceID {
+			t.Errorf("got ErrorResponse.TraceID %s, expected %s", resp.TraceID, traceID)
+		}
+		if resp.RequestID != "4bf92f35-77b3-4da6-a3ce-929d0e0e4736" {
+			t.Errorf("RequestID %s should be derived from trace id", resp.RequestID)
+		}
+	})
+	t.Run("should render handler error once and record it on span", func(t *testing.T) {
+		exp.Reset()
+		rendered = 0
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		if rec.Code != http.StatusNotFound || rendered != 1 {
+			t.Errorf("got status %d rendered %d times, expected %d once", rec.Code, rendered, http.StatusNotFound)
+		}
+		spans := exp.GetSpans()
+		if len(spans) != 1 || len(spans[0].Events) != 1 || spans[0].Events[0].Name != "exception" {
+			t.Errorf("got spans %+v, expected span with recorded error", spans)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package api
+
+// LogLevel is logger level model of admin endpoint
+type LogLevel struct {
+	// Logger is name of logger (empty for root logger)
+	Logger string `json:"logger,omitempty"`
+	// Level is logging level (panic, fatal, error, warn, info, debug, trace)
+	Level string `json:"level"`
+}
+
+// ModuleInfo is go module dependency
+type ModuleInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// BuildInfoResponse response of version endpoint
+type BuildInfoResponse struct {
+	// Name is service name reported by ServerHeader
+	Name string `json:"name"`
+	// Version is service version reported by ServerHeader
+	Version string `json:"version"`
+	// GoVersion is version of go toolchain used for build
+	GoVersion string `json:"go_version"`
+	// Module is path of main module
+	Module string `json:"module,omitempty"`
+	// Revision is VCS revision of build
+	Revision string `json:"revision,omitempty"`
+	// RevisionTime is VCS commit time of build
+	RevisionTime string `json:"revision_time,omitempty"`
+	// Modified is true if working tree had local changes
+	Modified bool `json:"modified,omitempty"`
+	// Deps are module dependencies
+	Deps []ModuleInfo `json:"deps,omitempty"`
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/shuvava/go-logging/logger"
 	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/tracing"
 )
 
 // ErrorResponse is http error response model
@@ -18,6 +19,10 @@ type ErrorResponse struct {
 	Description string `json:"description"`
 	// RequestID HTTP requestID go from header of request
 	RequestID string `json:"request_id"`
+	// TraceID distributed trace id of request
+	TraceID string `json:"trace_id,omitempty"`
+	// Details per-field validation errors
+	Details []apperrors.FieldError `json:"details,omitempty"`
 }
 
 // NewErrorResponse creates new error response from error
@@ -26,12 +31,14 @@ func NewErrorResponse(ctx context.Context, statusCode int, err error) ErrorRespo
 	resp := ErrorResponse{
 		StatusCode: statusCode,
 		RequestID:  requestID,
+		TraceID:    tracing.TraceID(ctx),
 	}
 
 	var typedErr apperrors.AppError
 	if errors.As(err, &typedErr) {
 		resp.ErrorCode = string(typedErr.ErrorCode)
 		resp.Description = typedErr.Description
+		resp.Details = typedErr.Details
 	} else {
 		resp.ErrorCode = apperrors.ErrorGeneric
 		resp.Description = err.Error()
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Page is a generic paginated response envelope
+type Page[T any] struct {
+	// Items page items
+	Items []T `json:"items"`
+	// Total number of items matched to request
+	Total int64 `json:"total"`
+	// Limit maximum number of items in page
+	Limit int64 `json:"limit"`
+	// Offset number of skipped items
+	Offset int64 `json:"offset"`
+	// Next link to the next page (empty on the last page)
+	Next string `json:"next,omitempty"`
+}
+
+// NewPage creates paginated response for request
+func NewPage[T any](ctx echo.Context, q ListQuery, items []T, total int64) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	page := Page[T]{
+		Items:  items,
+		Total:  total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	next := q.Offset + int64(len(items))
+	if len(items) > 0 && next < total {
+		u := *ctx.Request().URL
+		params := u.Query()
+		params.Set(QueryOffset, strconv.FormatInt(next, 10))
+		params.Set(QueryLimit, strconv.FormatInt(q.Limit, 10))
+		u.RawQuery = params.Encode()
+		page.Next = u.RequestURI()
+	}
+	return page
+}
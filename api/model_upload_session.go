@@ -0,0 +1,68 @@
+package api
+
+import (
+	"time"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+// UploadSessionStatus is status of multipart upload session
+type UploadSessionStatus string
+
+const (
+	// UploadSessionOpen is status of session accepting parts
+	UploadSessionOpen UploadSessionStatus = "open"
+	// UploadSessionCompleting is status of session while its parts are concatenated
+	UploadSessionCompleting UploadSessionStatus = "completing"
+	// UploadSessionCompleted is status of session with verified and stored content
+	UploadSessionCompleted UploadSessionStatus = "completed"
+)
+
+// CreateUploadSessionRequest is request of multipart upload session creation
+type CreateUploadSessionRequest struct {
+	// Size is expected size of content in bytes (not checked if 0)
+	Size int64 `json:"size,omitempty"`
+	// SHA256 is expected hex encoded SHA-256 digest of content
+	SHA256 string `json:"sha256" validate:"required,hex:64"`
+}
+
+// UploadPart is uploaded part of multipart upload session
+type UploadPart struct {
+	// Number is number of part (parts are concatenated in ascending order of numbers)
+	Number int `json:"number" bson:"number"`
+	// Size is size of part in bytes
+	Size int64 `json:"size" bson:"size"`
+	// SHA256 is hex encoded SHA-256 digest of part
+	SHA256 string `json:"sha256" bson:"sha256"`
+	// Key is key of part content in UploadPartStorage
+	Key string `json:"-" bson:"key"`
+	// UploadedAt is time of part upload
+	UploadedAt time.Time `json:"uploaded_at" bson:"uploadedAt"`
+}
+
+// UploadSession is multipart upload session
+type UploadSession struct {
+	// ID is session id
+	ID string `json:"id" bson:"_id"`
+	// Namespace is OTA namespace of session
+	Namespace data.Namespace `json:"namespace" bson:"namespace"`
+	// Status is status of session
+	Status UploadSessionStatus `json:"status" bson:"status"`
+	// Size is expected size of content in bytes (not checked if 0)
+	Size int64 `json:"size,omitempty" bson:"size"`
+	// SHA256 is expected hex encoded SHA-256 digest of content
+	SHA256 string `json:"sha256" bson:"sha256"`
+	// Parts are uploaded parts ordered by number
+	Parts []UploadPart `json:"parts" bson:"-"`
+	// CreatedAt is time of session creation
+	CreatedAt time.Time `json:"created_at" bson:"createdAt"`
+	// ExpiresAt is time of session expiration (content of expired session parts is removed)
+	ExpiresAt time.Time `json:"expires_at" bson:"expiresAt"`
+	// LockedUntil is time of lease expiration of completing session
+	LockedUntil time.Time `json:"-" bson:"lockedUntil,omitempty"`
+}
+
+// LeaseExpired reports if session is completing and its lease is expired (ex. instance crashed during completion)
+func (s *UploadSession) LeaseExpired(now time.Time) bool {
+	return s.Status == UploadSessionCompleting && !s.LockedUntil.After(now)
+}
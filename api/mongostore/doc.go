@@ -0,0 +1,3 @@
+// Package mongostore contains MongoDB implementations of api package stores
+// (idempotency records, rate limiter state and upload sessions)
+package mongostore
@@ -0,0 +1,134 @@
+package mongostore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// IdempotencyCollection is default name of idempotency records collection
+const IdempotencyCollection = "idempotency_keys"
+
+// IdempotencyStore is mongo implementation of api.IdempotencyStore,
+// expired records are removed by TTL index
+type IdempotencyStore struct {
+	repo mongo.ExtendedMongoRepository
+	coll *driver.Collection
+}
+
+// NewIdempotencyStore creates IdempotencyStore and ensures indexes of its collection
+func NewIdempotencyStore(ctx context.Context, repo mongo.ExtendedMongoRepository, collection string) (*IdempotencyStore, error) {
+	if collection == "" {
+		collection = IdempotencyCollection
+	}
+	s := &IdempotencyStore{
+		repo: repo,
+		coll: repo.GetCollection(collection),
+	}
+	indexes := []driver.IndexModel{
+		{
+			Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if err := repo.CreateIndexes(ctx, s.coll, indexes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Begin stores not completed record if its key is not used or lease of not completed record is expired,
+// otherwise it returns existing record
+func (s *IdempotencyStore) Begin(ctx context.Context, rec *api.IdempotencyRecord) (*api.IdempotencyRecord, error) {
+	for attempt := 0; ; attempt++ {
+		_, err := s.repo.InsertOne(ctx, s.coll, rec)
+		if err == nil {
+			return nil, nil
+		}
+		if apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbAlreadyExist {
+			return nil, err
+		}
+		now := time.Now()
+		existing, err := s.Get(ctx, rec.Namespace, rec.Key)
+		if err != nil || (existing != nil && !existing.LeaseExpired(now)) || attempt > 0 {
+			return existing, err
+		}
+		if existing != nil {
+			// take over key of request not completed in time
+			filter := append(keyFilter(rec.Namespace, rec.Key),
+				bson.E{Key: "completed", Value: false},
+				bson.E{Key: "lockedUntil", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: now}}}}})
+			err = s.repo.ReplaceOne(ctx, s.coll, filter, rec)
+			if err == nil {
+				return nil, nil
+			}
+			if apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbNoDocumentFound {
+				return nil, err
+			}
+			continue
+		}
+		// record is expired but not yet removed by TTL monitor
+		filter := bson.D{
+			{Key: "namespace", Value: rec.Namespace},
+			{Key: "key", Value: rec.Key},
+			{Key: "expiresAt", Value: bson.D{{Key: "$lte", Value: now}}},
+		}
+		if err = s.repo.Delete(ctx, s.coll, filter); err != nil &&
+			apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbNoDocumentFound {
+			return nil, err
+		}
+	}
+}
+
+// Get returns record by key (nil if record does not exist)
+func (s *IdempotencyStore) Get(ctx context.Context, ns data.Namespace, key string) (*api.IdempotencyRecord, error) {
+	var rec api.IdempotencyRecord
+	err := s.repo.GetOne(ctx, s.coll, keyFilter(ns, key), &rec)
+	if err != nil {
+		if apperrors.ToAppError(err).ErrorCode == apperrors.ErrorDbNoDocumentFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if rec.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// Complete stores response of record
+func (s *IdempotencyStore) Complete(ctx context.Context, rec *api.IdempotencyRecord) error {
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "completed", Value: true},
+		{Key: "statusCode", Value: rec.StatusCode},
+		{Key: "header", Value: rec.Header},
+		{Key: "body", Value: rec.Body},
+	}}}
+	return s.repo.UpdateOne(ctx, s.coll, keyFilter(rec.Namespace, rec.Key), update)
+}
+
+// Release removes not completed record allowing to retry request
+func (s *IdempotencyStore) Release(ctx context.Context, ns data.Namespace, key string) error {
+	filter := append(keyFilter(ns, key), bson.E{Key: "completed", Value: false})
+	err := s.repo.Delete(ctx, s.coll, filter)
+	if err != nil && apperrors.ToAppError(err).ErrorCode == apperrors.ErrorDbNoDocumentFound {
+		return nil
+	}
+	return err
+}
+
+func keyFilter(ns data.Namespace, key string) bson.D {
+	return bson.D{{Key: "namespace", Value: ns}, {Key: "key", Value: key}}
+}
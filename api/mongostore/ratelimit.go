@@ -0,0 +1,132 @@
+package mongostore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// RateLimitCollection is default name of rate limiter state collection
+const RateLimitCollection = "rate_limits"
+
+// RateLimitStore is mongo implementation of api.RateLimitStore,
+// state is updated by single findAndModify with update pipeline, expired states are removed by TTL index
+type RateLimitStore struct {
+	repo mongo.ExtendedMongoRepository
+	coll *driver.Collection
+}
+
+type rateLimitDocument struct {
+	Key       string             `bson:"_id"`
+	State     api.RateLimitState `bson:"state"`
+	Allowed   bool               `bson:"allowed"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// NewRateLimitStore creates RateLimitStore and ensures indexes of its collection
+func NewRateLimitStore(ctx context.Context, repo mongo.ExtendedMongoRepository, collection string) (*RateLimitStore, error) {
+	if collection == "" {
+		collection = RateLimitCollection
+	}
+	s := &RateLimitStore{
+		repo: repo,
+		coll: repo.GetCollection(collection),
+	}
+	indexes := []driver.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if err := repo.CreateIndexes(ctx, s.coll, indexes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Take atomically applies algorithm to state of key consuming one request if it is allowed
+func (s *RateLimitStore) Take(ctx context.Context, key string, algorithm api.RateLimitAlgorithm, limit api.RateLimit, now time.Time, ttl time.Duration) (api.RateLimitState, bool, error) {
+	var pipeline bson.A
+	if algorithm == api.SlidingWindow {
+		pipeline = slidingWindowPipeline(limit, now)
+	} else {
+		pipeline = tokenBucketPipeline(limit, now)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.D{
+		{Key: "state.updated", Value: now},
+		{Key: "expiresAt", Value: now.Add(ttl)},
+	}}})
+	filter := bson.D{{Key: "_id", Value: key}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var doc rateLimitDocument
+	err := s.repo.FindOneAndUpdate(ctx, s.coll, filter, pipeline, opts, &doc)
+	if err != nil && apperrors.ToAppError(err).ErrorCode == apperrors.ErrorDbAlreadyExist {
+		// concurrent request inserted state of key first, retry updates existing document
+		err = s.repo.FindOneAndUpdate(ctx, s.coll, filter, pipeline, opts, &doc)
+	}
+	if err != nil {
+		return api.RateLimitState{}, false, err
+	}
+	return doc.State, doc.Allowed, nil
+}
+
+// tokenBucketPipeline returns update pipeline of token bucket algorithm (see api.ApplyRateLimit)
+func tokenBucketPipeline(limit api.RateLimit, now time.Time) bson.A {
+	capacity := float64(limit.Burst)
+	ratePerMs := float64(limit.Limit) / float64(limit.Window.Milliseconds())
+	elapsedMs := bson.D{{Key: "$max", Value: bson.A{0, bson.D{{Key: "$subtract", Value: bson.A{
+		now, bson.D{{Key: "$ifNull", Value: bson.A{"$state.updated", now}}},
+	}}}}}}
+	tokens := bson.D{{Key: "$min", Value: bson.A{capacity, bson.D{{Key: "$add", Value: bson.A{
+		bson.D{{Key: "$ifNull", Value: bson.A{"$state.tokens", capacity}}},
+		bson.D{{Key: "$multiply", Value: bson.A{elapsedMs, ratePerMs}}},
+	}}}}}}
+	return bson.A{
+		bson.D{{Key: "$set", Value: bson.D{{Key: "state.tokens", Value: tokens}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "allowed", Value: bson.D{{Key: "$gte", Value: bson.A{"$state.tokens", 1}}}}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "state.tokens", Value: bson.D{{Key: "$cond", Value: bson.A{
+			"$allowed", bson.D{{Key: "$subtract", Value: bson.A{"$state.tokens", 1}}}, "$state.tokens",
+		}}}}}}},
+	}
+}
+
+// slidingWindowPipeline returns update pipeline of sliding window algorithm (see api.ApplyRateLimit)
+func slidingWindowPipeline(limit api.RateLimit, now time.Time) bson.A {
+	windowStart := now.Truncate(limit.Window)
+	weight := 1 - now.Sub(windowStart).Seconds()/limit.Window.Seconds()
+	inWindow := func(start time.Time) bson.D {
+		return bson.D{{Key: "$eq", Value: bson.A{"$state.windowStart", start}}}
+	}
+	valueOf := func(field string) bson.D {
+		return bson.D{{Key: "$ifNull", Value: bson.A{field, 0}}}
+	}
+	// expressions of the same stage are evaluated against state before the stage
+	rollover := bson.D{
+		{Key: "state.prevCount", Value: bson.D{{Key: "$switch", Value: bson.D{
+			{Key: "branches", Value: bson.A{
+				bson.D{{Key: "case", Value: inWindow(windowStart)}, {Key: "then", Value: valueOf("$state.prevCount")}},
+				bson.D{{Key: "case", Value: inWindow(windowStart.Add(-limit.Window))}, {Key: "then", Value: valueOf("$state.count")}},
+			}},
+			{Key: "default", Value: 0},
+		}}}},
+		{Key: "state.count", Value: bson.D{{Key: "$cond", Value: bson.A{inWindow(windowStart), valueOf("$state.count"), 0}}}},
+		{Key: "state.windowStart", Value: windowStart},
+	}
+	estimated := bson.D{{Key: "$add", Value: bson.A{
+		bson.D{{Key: "$multiply", Value: bson.A{"$state.prevCount", weight}}}, "$state.count", 1,
+	}}}
+	return bson.A{
+		bson.D{{Key: "$set", Value: rollover}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "allowed", Value: bson.D{{Key: "$lte", Value: bson.A{estimated, float64(limit.Limit)}}}}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "state.count", Value: bson.D{{Key: "$cond", Value: bson.A{
+			"$allowed", bson.D{{Key: "$add", Value: bson.A{"$state.count", 1}}}, "$state.count",
+		}}}}}}},
+	}
+}
@@ -0,0 +1,165 @@
+package mongostore
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// UploadSessionCollection is default name of upload sessions collection
+const UploadSessionCollection = "upload_sessions"
+
+// UploadSessionStore is mongo implementation of api.UploadSessionStore,
+// expired sessions are removed by api.UploadSessions cleanup (with content of their parts)
+type UploadSessionStore struct {
+	repo mongo.ExtendedMongoRepository
+	coll *driver.Collection
+}
+
+// uploadSessionDocument is stored upload session, parts are keyed by number to be updated independently
+type uploadSessionDocument struct {
+	api.UploadSession `bson:",inline"`
+	Parts             map[string]api.UploadPart `bson:"parts"`
+}
+
+// NewUploadSessionStore creates UploadSessionStore and ensures indexes of its collection
+func NewUploadSessionStore(ctx context.Context, repo mongo.ExtendedMongoRepository, collection string) (*UploadSessionStore, error) {
+	if collection == "" {
+		collection = UploadSessionCollection
+	}
+	s := &UploadSessionStore{
+		repo: repo,
+		coll: repo.GetCollection(collection),
+	}
+	indexes := []driver.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "status", Value: 1}, {Key: "expiresAt", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}},
+	}
+	if err := repo.CreateIndexes(ctx, s.coll, indexes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create stores new session
+func (s *UploadSessionStore) Create(ctx context.Context, session *api.UploadSession) error {
+	doc := uploadSessionDocument{
+		UploadSession: *session,
+		Parts:         make(map[string]api.UploadPart, len(session.Parts)),
+	}
+	for _, p := range session.Parts {
+		doc.Parts[strconv.Itoa(p.Number)] = p
+	}
+	_, err := s.repo.InsertOne(ctx, s.coll, doc)
+	return err
+}
+
+// Active returns number of not completed and not expired sessions of namespace
+func (s *UploadSessionStore) Active(ctx context.Context, ns data.Namespace, now time.Time) (int64, error) {
+	filter := bson.D{
+		{Key: "namespace", Value: ns},
+		{Key: "status", Value: bson.D{{Key: "$ne", Value: api.UploadSessionCompleted}}},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+	return s.repo.Count(ctx, s.coll, filter)
+}
+
+// Get returns session
+func (s *UploadSessionStore) Get(ctx context.Context, ns data.Namespace, id string) (*api.UploadSession, error) {
+	var doc uploadSessionDocument
+	if err := s.repo.GetOne(ctx, s.coll, sessionFilter(ns, id), &doc); err != nil {
+		return nil, err
+	}
+	session := doc.session()
+	return &session, nil
+}
+
+// PutPart stores part of open session replacing part with the same number
+func (s *UploadSessionStore) PutPart(ctx context.Context, ns data.Namespace, id string, part api.UploadPart) error {
+	filter := append(sessionFilter(ns, id), bson.E{Key: "status", Value: api.UploadSessionOpen})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "parts." + strconv.Itoa(part.Number), Value: part}}}}
+	err := s.repo.UpdateOne(ctx, s.coll, filter, update)
+	return s.stateConflict(ctx, ns, id, err)
+}
+
+// SetStatus changes status of session
+func (s *UploadSessionStore) SetStatus(ctx context.Context, ns data.Namespace, id string, from, to api.UploadSessionStatus) error {
+	filter := append(sessionFilter(ns, id), bson.E{Key: "status", Value: from})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: to}}}}
+	err := s.repo.UpdateOne(ctx, s.coll, filter, update)
+	return s.stateConflict(ctx, ns, id, err)
+}
+
+// LockCompletion changes status of open session or completing session with expired lease to completing
+func (s *UploadSessionStore) LockCompletion(ctx context.Context, ns data.Namespace, id string, now, lockedUntil time.Time) error {
+	filter := append(sessionFilter(ns, id), bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "status", Value: api.UploadSessionOpen}},
+		bson.D{
+			{Key: "status", Value: api.UploadSessionCompleting},
+			{Key: "lockedUntil", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: now}}}}},
+		},
+	}})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: api.UploadSessionCompleting},
+		{Key: "lockedUntil", Value: lockedUntil},
+	}}}
+	err := s.repo.UpdateOne(ctx, s.coll, filter, update)
+	return s.stateConflict(ctx, ns, id, err)
+}
+
+// Delete removes session
+func (s *UploadSessionStore) Delete(ctx context.Context, ns data.Namespace, id string) error {
+	return s.repo.Delete(ctx, s.coll, sessionFilter(ns, id))
+}
+
+// Expired returns up to limit sessions expired before now
+func (s *UploadSessionStore) Expired(ctx context.Context, now time.Time, limit int) ([]api.UploadSession, error) {
+	filter := bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$lte", Value: now}}}}
+	var docs []uploadSessionDocument
+	if err := s.repo.FindWithOptions(ctx, s.coll, filter, options.Find().SetLimit(int64(limit)), &docs); err != nil {
+		return nil, err
+	}
+	res := make([]api.UploadSession, 0, len(docs))
+	for _, doc := range docs {
+		res = append(res, doc.session())
+	}
+	return res, nil
+}
+
+// stateConflict converts ErrorDbNoDocumentFound to ErrorSvcInvalidState if session still exists
+func (s *UploadSessionStore) stateConflict(ctx context.Context, ns data.Namespace, id string, err error) error {
+	if err == nil || apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbNoDocumentFound {
+		return err
+	}
+	cnt, cntErr := s.repo.Count(ctx, s.coll, sessionFilter(ns, id))
+	if cntErr != nil || cnt == 0 {
+		return err
+	}
+	return apperrors.NewAppError(apperrors.ErrorSvcInvalidState,
+		"upload session status was changed concurrently")
+}
+
+// session converts stored document to upload session with parts ordered by number
+func (doc uploadSessionDocument) session() api.UploadSession {
+	session := doc.UploadSession
+	session.Parts = make([]api.UploadPart, 0, len(doc.Parts))
+	for _, p := range doc.Parts {
+		session.Parts = append(session.Parts, p)
+	}
+	sort.Slice(session.Parts, func(i, j int) bool { return session.Parts[i].Number < session.Parts[j].Number })
+	return session
+}
+
+func sessionFilter(ns data.Namespace, id string) bson.D {
+	return bson.D{{Key: "_id", Value: id}, {Key: "namespace", Value: ns}}
+}
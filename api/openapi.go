@@ -0,0 +1,501 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// OpenAPIPath is path of OpenAPI document endpoint
+	OpenAPIPath = "/openapi.json"
+	// OpenAPIVersion is version of OpenAPI specification of generated document
+	OpenAPIVersion = "3.1.0"
+	// DefaultOpenAPISecurityScheme is name of default bearer token security scheme
+	DefaultOpenAPISecurityScheme = "bearerAuth"
+	// OpenAPIWildcardParam is name of path parameter of echo wildcard route segment (ex. /files/* is /files/{path})
+	OpenAPIWildcardParam = "path"
+
+	// ParamInPath is location of path parameter
+	ParamInPath = "path"
+	// ParamInQuery is location of query parameter
+	ParamInQuery = "query"
+	// ParamInHeader is location of header parameter
+	ParamInHeader = "header"
+
+	openAPISchemaRef = "#/components/schemas/"
+)
+
+var (
+	// openAPIMethods are http methods supported by OpenAPI path item
+	openAPIMethods = map[string]bool{
+		http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+		http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+	}
+	// typeQualifier matches package path of type name (ex. "github.com/org/pkg." in generic type arguments)
+	typeQualifier   = regexp.MustCompile(`[^\[\],*]*\.`)
+	invalidNameChar = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// RouteDoc is OpenAPI metadata of route
+type RouteDoc struct {
+	// OperationID is unique id of operation
+	OperationID string
+	// Summary is short summary of operation
+	Summary string
+	// Description is description of operation
+	Description string
+	// Tags are used to group operations
+	Tags []string
+	// Params are query and header parameters of operation,
+	// path parameters are derived from route path (listed params override them),
+	// echo wildcard * is described as OpenAPIWildcardParam parameter
+	Params []ParamDoc
+	// Request is value of request body type (ex. CreateUploadSessionRequest{}), nil if operation has no body
+	Request interface{}
+	// RequestContentType is media type of request body (application/json if empty)
+	RequestContentType string
+	// Responses are values of response body types by status code (nil value for response without body),
+	// error responses are documented by ErrorResponse default response
+	Responses map[int]interface{}
+	// ResponseContentType is media type of responses (application/json if empty)
+	ResponseContentType string
+	// Scopes are auth scopes required by operation, operation is public if nil
+	Scopes []string
+	// Deprecated marks operation as deprecated
+	Deprecated bool
+	// Hidden excludes operation from document (ex. admin endpoints)
+	Hidden bool
+}
+
+// ParamDoc is OpenAPI metadata of operation parameter
+type ParamDoc struct {
+	// Name of parameter
+	Name string
+	// In is location of parameter (ParamInPath, ParamInQuery or ParamInHeader)
+	In string
+	// Description of parameter
+	Description string
+	// Required is true if parameter is mandatory (path parameters are always required)
+	Required bool
+	// Type is value of parameter type (string if nil)
+	Type interface{}
+}
+
+// OpenAPIConfig is configuration of OpenAPI document
+type OpenAPIConfig struct {
+	// Title is title of API
+	Title string
+	// Version is version of API
+	Version string
+	// Description is description of API
+	Description string
+	// Servers are base URLs of API
+	Servers []string
+	// SecuritySchemes are security schemes of API (bearer JWT scheme if empty)
+	SecuritySchemes map[string]OpenAPISecurityScheme
+	// SecurityScheme is name of security scheme of route scopes (DefaultOpenAPISecurityScheme if empty)
+	SecurityScheme string
+}
+
+// DefaultOpenAPIConfig returns default OpenAPIConfig
+func DefaultOpenAPIConfig() OpenAPIConfig {
+	return OpenAPIConfig{
+		SecuritySchemes: map[string]OpenAPISecurityScheme{
+			DefaultOpenAPISecurityScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+		SecurityScheme: DefaultOpenAPISecurityScheme,
+	}
+}
+
+// OpenAPI builds OpenAPI document of echo routes described by RouteDoc
+type OpenAPI struct {
+	cfg    OpenAPIConfig
+	mu     sync.RWMutex
+	routes map[string]RouteDoc
+}
+
+// NewOpenAPI creates OpenAPI
+func NewOpenAPI(cfg OpenAPIConfig) *OpenAPI {
+	def := DefaultOpenAPIConfig()
+	if len(cfg.SecuritySchemes) == 0 {
+		cfg.SecuritySchemes = def.SecuritySchemes
+	}
+	if cfg.SecurityScheme == "" {
+		cfg.SecurityScheme = def.SecurityScheme
+	}
+	return &OpenAPI{
+		cfg:    cfg,
+		routes: make(map[string]RouteDoc),
+	}
+}
+
+// Describe sets metadata of route, it returns the route to be used with route registration:
+//
+//	spec.Describe(g.POST("", h.create), api.RouteDoc{Request: CreateRequest{}, Responses: ...})
+func (o *OpenAPI) Describe(r *echo.Route, doc RouteDoc) *echo.Route {
+	o.DescribeRoute(r.Method, r.Path, doc)
+	return r
+}
+
+// DescribeRoute sets metadata of route by method and path template (ex. "GET /devices/:id")
+func (o *OpenAPI) DescribeRoute(method, path string, doc RouteDoc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.routes[method+" "+path] = doc
+}
+
+// Document builds OpenAPI document of routes, routes without metadata are documented by path and method only
+func (o *OpenAPI) Document(routes []*echo.Route) *OpenAPIDocument {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	b := newSchemaBuilder()
+	b.schema(reflect.TypeOf(ErrorResponse{}))
+	b.schema(reflect.TypeOf(HealthStatusResponse{}))
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       o.cfg.Title,
+			Version:     o.cfg.Version,
+			Description: o.cfg.Description,
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{
+			Schemas:         b.schemas,
+			SecuritySchemes: o.cfg.SecuritySchemes,
+		},
+	}
+	for _, u := range o.cfg.Servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: u})
+	}
+	for _, r := range routes {
+		if !openAPIMethods[r.Method] || o.routes[r.Method+" "+r.Path].Hidden {
+			continue
+		}
+		p, params := openAPIPath(r.Path)
+		item, ok := doc.Paths[p]
+		if !ok {
+			item = make(map[string]*OpenAPIOperation)
+			doc.Paths[p] = item
+		}
+		item[strings.ToLower(r.Method)] = o.operation(b, o.routes[r.Method+" "+r.Path], params)
+	}
+	return doc
+}
+
+// Handler returns endpoint serving OpenAPI document of routes of e,
+// document is built on first request (routes should be registered before server start)
+func (o *OpenAPI) Handler(e *echo.Echo) echo.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c echo.Context) error {
+		once.Do(func() {
+			body, err = json.Marshal(o.Document(e.Routes()))
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSONBlob(http.StatusOK, body)
+	}
+}
+
+// Register adds OpenAPIPath endpoint serving OpenAPI document of routes of e
+func (o *OpenAPI) Register(e *echo.Echo) *echo.Route {
+	return o.Describe(e.GET(OpenAPIPath, o.Handler(e)), RouteDoc{
+		Summary:   "OpenAPI document of service",
+		Tags:      []string{"meta"},
+		Responses: map[int]interface{}{http.StatusOK: map[string]interface{}{}},
+	})
+}
+
+// operation builds operation of route from its metadata
+func (o *OpenAPI) operation(b *schemaBuilder, doc RouteDoc, pathParams []string) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: doc.OperationID,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Responses:   make(map[string]OpenAPIResponse),
+	}
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, OpenAPIParameter{
+			Name:     name,
+			In:       ParamInPath,
+			Required: true,
+			Schema:   &OpenAPISchema{Type: "string"},
+		})
+	}
+	for _, p := range doc.Params {
+		param := OpenAPIParameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == ParamInPath,
+			Schema:      &OpenAPISchema{Type: "string"},
+		}
+		if p.Type != nil {
+			param.Schema = b.schema(reflect.TypeOf(p.Type))
+		}
+		replaced := false
+		for i := range op.Parameters {
+			if op.Parameters[i].Name == p.Name && op.Parameters[i].In == p.In {
+				op.Parameters[i], replaced = param, true
+			}
+		}
+		if !replaced {
+			op.Parameters = append(op.Parameters, param)
+		}
+	}
+
+	if doc.Request != nil {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  mediaType(doc.RequestContentType, b.schema(reflect.TypeOf(doc.Request))),
+		}
+	}
+	for code, v := range doc.Responses {
+		resp := OpenAPIResponse{Description: http.StatusText(code)}
+		if v != nil {
+			resp.Content = mediaType(doc.ResponseContentType, b.schema(reflect.TypeOf(v)))
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+	if len(doc.Responses) == 0 {
+		op.Responses[strconv.Itoa(http.StatusOK)] = OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	}
+	op.Responses["default"] = OpenAPIResponse{
+		Description: "Error response",
+		Content:     mediaType("", &OpenAPISchema{Ref: openAPISchemaRef + "ErrorResponse"}),
+	}
+
+	if doc.Scopes != nil {
+		op.Security = []map[string][]string{{o.cfg.SecurityScheme: append([]string{}, doc.Scopes...)}}
+	}
+	return op
+}
+
+// openAPIPath converts echo route path to OpenAPI path template, it returns names of path parameters,
+// wildcard segment is named OpenAPIWildcardParam ("*" is not valid OpenAPI parameter name)
+func openAPIPath(p string) (string, []string) {
+	if p == "" {
+		return "/", nil
+	}
+	var params []string
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		case s == "*":
+			params = append(params, OpenAPIWildcardParam)
+			segments[i] = "{" + OpenAPIWildcardParam + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func mediaType(contentType string, s *OpenAPISchema) map[string]OpenAPIMediaType {
+	if contentType == "" {
+		contentType = echo.MIMEApplicationJSON
+	}
+	return map[string]OpenAPIMediaType{contentType: {Schema: s}}
+}
+
+// schemaBuilder derives JSON schemas from go types, named struct types are added to components
+type schemaBuilder struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	b := &schemaBuilder{
+		schemas: make(map[string]*OpenAPISchema),
+		names:   make(map[reflect.Type]string),
+	}
+	b.names[reflect.TypeOf(data.CorrelationID{})] = "CorrelationID"
+	b.schemas["CorrelationID"] = &OpenAPISchema{Type: "string", Format: "uuid"}
+	return b
+}
+
+// schema returns schema of type (reference to components for named struct types)
+func (b *schemaBuilder) schema(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name, ok := b.names[t]; ok {
+		return &OpenAPISchema{Ref: openAPISchemaRef + name}
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(time.Duration(0)):
+		return &OpenAPISchema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case reflect.TypeOf(Duration(0)):
+		return &OpenAPISchema{Type: "string", Description: "duration (ex. 1.5ms)"}
+	case reflect.TypeOf(uuid.UUID{}):
+		return &OpenAPISchema{Type: "string", Format: "uuid"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return &OpenAPISchema{}
+	}
+	if t.Kind() != reflect.String && reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		minimum := float64(0)
+		return &OpenAPISchema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &OpenAPISchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			s := &OpenAPISchema{Type: "object"}
+			b.properties(t, s)
+			return s
+		}
+		name := b.componentName(t)
+		s := &OpenAPISchema{Type: "object"}
+		b.names[t] = name
+		b.schemas[name] = s
+		b.properties(t, s)
+		return &OpenAPISchema{Ref: openAPISchemaRef + name}
+	default:
+		// interfaces and other types accept any value
+		return &OpenAPISchema{}
+	}
+}
+
+// properties adds properties of struct fields serialized by encoding/json to schema
+func (b *schemaBuilder) properties(t reflect.Type, s *OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			b.properties(ft, s)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := b.schema(f.Type)
+		if strings.Contains(opts, "string") {
+			fs = &OpenAPISchema{Type: "string"}
+		}
+		if applyValidateTag(fs, ft, f.Tag.Get(ValidateTag)) {
+			s.Required = append(s.Required, name)
+		}
+		if s.Properties == nil {
+			s.Properties = make(map[string]*OpenAPISchema)
+		}
+		s.Properties[name] = fs
+	}
+	sort.Strings(s.Required)
+}
+
+// componentName returns unique name of type in components
+// (package qualifiers of generic type arguments are removed, ex. Page[api.Device] is Page_Device)
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := typeQualifier.ReplaceAllString(t.Name(), "")
+	name = strings.Trim(invalidNameChar.ReplaceAllString(name, "_"), "_")
+	if _, found := b.schemas[name]; found {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	return name
+}
+
+// applyValidateTag adds constraints of ValidateTag rules to schema, it returns true if value is required
+func applyValidateTag(s *OpenAPISchema, t reflect.Type, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	required := false
+	isString := t.Kind() == reflect.String
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), ruleArgSeparator)
+		switch name {
+		case ruleRequired:
+			required = true
+		case ruleLen:
+			minStr, maxStr, isRange := strings.Cut(arg, ruleArgSeparator)
+			if !isRange {
+				maxStr = minStr
+			}
+			minVal, errMin := strconv.Atoi(minStr)
+			maxVal, errMax := strconv.Atoi(maxStr)
+			if errMin != nil || errMax != nil {
+				continue
+			}
+			if isString {
+				s.MinLength, s.MaxLength = &minVal, &maxVal
+			} else if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				s.MinItems, s.MaxItems = &minVal, &maxVal
+			}
+		case ruleHex:
+			s.Pattern = "^[0-9a-f]*$"
+			if arg != "" {
+				s.Pattern = "^[0-9a-f]{" + arg + "}$"
+			}
+		case ruleBase64:
+			s.ContentEncoding = "base64"
+		case ruleUUID:
+			s.Format = "uuid"
+		case ruleEnum:
+			for _, v := range strings.Split(arg, ruleEnumSeparator) {
+				if isString {
+					s.Enum = append(s.Enum, v)
+				} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+					s.Enum = append(s.Enum, n)
+				}
+			}
+		}
+	}
+	return required
+}
@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+type device struct {
+	ID        data.CorrelationID `json:"id"`
+	Name      string             `json:"name" validate:"required,len:1:64"`
+	Hash      string             `json:"hash,omitempty" validate:"hex:64"`
+	Status    string             `json:"status" validate:"enum:online|offline"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Internal  string             `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	spec := api.NewOpenAPI(api.OpenAPIConfig{Title: "devices", Version: "1.0.0"})
+	e := echo.New()
+	noop := func(echo.Context) error { return nil }
+	g := e.Group("/devices")
+	spec.Describe(g.GET("", noop), api.RouteDoc{
+		Summary:   "List devices",
+		Params:    []api.ParamDoc{{Name: api.QueryLimit, In: api.ParamInQuery, Type: int64(0)}},
+		Responses: map[int]interface{}{http.StatusOK: api.Page[device]{}},
+		Scopes:    []string{"devices:read"},
+	})
+	spec.Describe(g.PUT("/:id", noop), api.RouteDoc{
+		Params:    []api.ParamDoc{{Name: "id", In: api.ParamInPath, Type: data.CorrelationID{}}},
+		Request:   device{},
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	})
+	g.DELETE("/:id", noop)
+	g.GET("/:id/files/*", noop)
+	spec.Register(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.OpenAPIPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", rec.Code, http.StatusOK)
+	}
+	var doc api.OpenAPIDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	schemas := doc.Components.Schemas
+
+	t.Run("should describe operations of routes", func(t *testing.T) {
+		if doc.OpenAPI != api.OpenAPIVersion || doc.Info.Title != "devices" {
+			t.Errorf("got document %s %+v", doc.OpenAPI, doc.Info)
+		}
+		list := doc.Paths["/devices"]["get"]
+		if list == nil {
+			t.Fatalf("got paths %v", doc.Paths)
+		}
+		if ref := list.Responses["200"].Content[echo.MIMEApplicationJSON].Schema.Ref; ref != "#/components/schemas/Page_device" {
+			t.Errorf("got response schema %q", ref)
+		}
+		if ref := list.Responses["default"].Content[echo.MIMEApplicationJSON].Schema.Ref; ref != "#/components/schemas/ErrorResponse" {
+			t.Errorf("got error response schema %q", ref)
+		}
+		if len(list.Parameters) != 1 || list.Parameters[0].Schema.Type != "integer" {
+			t.Errorf("got parameters %+v", list.Parameters)
+		}
+		if len(list.Security) != 1 || list.Security[0][api.DefaultOpenAPISecurityScheme][0] != "devices:read" {
+			t.Errorf("got security %v", list.Security)
+		}
+		update := doc.Paths["/devices/{id}"]["put"]
+		if update == nil || update.RequestBody == nil || update.Responses["204"].Content != nil {
+			t.Fatalf("got operation %+v", update)
+		}
+		if p := update.Parameters; len(p) != 1 || !p[0].Required || p[0].Schema.Ref != "#/components/schemas/CorrelationID" {
+			t.Errorf("got parameters %+v", p)
+		}
+		if del := doc.Paths["/devices/{id}"]["delete"]; del == nil || del.Parameters[0].Name != "id" {
+			t.Errorf("got operation %+v", del)
+		}
+		files := doc.Paths["/devices/{id}/files/{path}"]["get"]
+		if files == nil || len(files.Parameters) != 2 || files.Parameters[1].Name != api.OpenAPIWildcardParam {
+			t.Errorf("got operation %+v", files)
+		}
+	})
+	t.Run("should derive schemas from types", func(t *testing.T) {
+		for _, name := range []string{"ErrorResponse", "HealthStatusResponse", "HealthEntryStatus", "FieldError"} {
+			if schemas[name] == nil {
+				t.Errorf("schema %s is missing", name)
+			}
+		}
+		if d := schemas["HealthEntryStatus"].Properties["duration"]; d == nil || d.Type != "string" {
+			t.Errorf("got duration schema %+v", d)
+		}
+		if id := schemas["CorrelationID"]; id == nil || id.Type != "string" || id.Format != "uuid" {
+			t.Errorf("got CorrelationID schema %+v", id)
+		}
+		dev := schemas["device"]
+		if dev == nil {
+			t.Fatalf("got schemas %v", schemas)
+		}
+		if len(dev.Properties) != 5 || len(dev.Required) != 1 || dev.Required[0] != "name" {
+			t.Errorf("got schema %+v", dev)
+		}
+		if name := dev.Properties["name"]; *name.MinLength != 1 || *name.MaxLength != 64 {
+			t.Errorf("got name schema %+v", name)
+		}
+		if hash := dev.Properties["hash"]; hash.Pattern != "^[0-9a-f]{64}$" {
+			t.Errorf("got hash schema %+v", hash)
+		}
+		if status := dev.Properties["status"]; len(status.Enum) != 2 {
+			t.Errorf("got status schema %+v", status)
+		}
+		if created := dev.Properties["createdAt"]; created.Format != "date-time" {
+			t.Errorf("got createdAt schema %+v", created)
+		}
+		if items := schemas["Page_device"].Properties["items"]; items.Type != "array" || items.Items.Ref != "#/components/schemas/device" {
+			t.Errorf("got items schema %+v", items)
+		}
+	})
+}
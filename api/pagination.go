@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// DefaultPageLimit is default number of items returned by list endpoint
+	DefaultPageLimit int64 = 50
+	// DefaultMaxPageLimit is default maximum number of items returned by list endpoint
+	DefaultMaxPageLimit int64 = 1000
+
+	// QueryLimit is query parameter name of page size
+	QueryLimit = "limit"
+	// QueryOffset is query parameter name of number of items to skip
+	QueryOffset = "offset"
+	// QuerySort is query parameter name of comma separated list of sort fields (prefix "-" is descending order)
+	QuerySort = "sort"
+	// QueryFields is query parameter name of comma separated list of returned fields
+	QueryFields = "fields"
+
+	sortDescPrefix = "-"
+	sortAscPrefix  = "+"
+)
+
+// ListQueryConfig defines defaults and restrictions of list query parameters
+type ListQueryConfig struct {
+	// DefaultLimit used if limit is not provided (DefaultPageLimit if 0)
+	DefaultLimit int64
+	// MaxLimit maximum allowed limit (DefaultMaxPageLimit if 0)
+	MaxLimit int64
+	// SortFields are fields allowed in sort parameter (sorting is disabled if empty)
+	SortFields []string
+	// DefaultSort used if sort is not provided
+	DefaultSort []SortField
+	// Fields are fields allowed in fields parameter (field selection is disabled if empty)
+	Fields []string
+}
+
+// SortField is a single sort criteria
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery is parsed and validated pagination, sorting and field-selection query parameters
+type ListQuery struct {
+	Limit  int64
+	Offset int64
+	Sort   []SortField
+	Fields []string
+}
+
+// ParseListQuery parses and validates list query parameters of request
+func ParseListQuery(ctx echo.Context, cfg ListQueryConfig) (ListQuery, error) {
+	if cfg.DefaultLimit <= 0 {
+		cfg.DefaultLimit = DefaultPageLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxPageLimit
+	}
+	q := ListQuery{
+		Limit: cfg.DefaultLimit,
+		Sort:  cfg.DefaultSort,
+	}
+	if q.Limit > cfg.MaxLimit {
+		q.Limit = cfg.MaxLimit
+	}
+
+	var err error
+	params := ctx.QueryParams()
+	if val := params.Get(QueryLimit); val != "" {
+		if q.Limit, err = parseQueryInt(QueryLimit, val, 1, cfg.MaxLimit); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	if val := params.Get(QueryOffset); val != "" {
+		if q.Offset, err = parseQueryInt(QueryOffset, val, 0, -1); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	if val := params.Get(QuerySort); val != "" {
+		if q.Sort, err = parseSort(val, cfg.SortFields); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	if val := params.Get(QueryFields); val != "" {
+		if q.Fields, err = parseFields(val, cfg.Fields); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+// FindOptions converts ListQuery to mongo find options
+func (q ListQuery) FindOptions() *options.FindOptions {
+	opts := options.Find().
+		SetSkip(q.Offset).
+		SetLimit(q.Limit)
+	if len(q.Sort) > 0 {
+		sort := bson.D{}
+		for _, s := range q.Sort {
+			order := 1
+			if s.Desc {
+				order = -1
+			}
+			sort = append(sort, bson.E{Key: s.Field, Value: order})
+		}
+		opts.SetSort(sort)
+	}
+	if len(q.Fields) > 0 {
+		projection := bson.D{}
+		for _, f := range q.Fields {
+			projection = append(projection, bson.E{Key: f, Value: 1})
+		}
+		opts.SetProjection(projection)
+	}
+	return opts
+}
+
+// parseQueryInt parses integer query parameter in range [lower, upper] (upper < 0 means no upper limit)
+func parseQueryInt(name, val string, lower, upper int64) (int64, error) {
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("query parameter %s should be integer, got %q", name, val))
+	}
+	if n < lower || (upper >= 0 && n > upper) {
+		if upper < 0 {
+			return 0, apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("query parameter %s should be greater or equal %d", name, lower))
+		}
+		return 0, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("query parameter %s should be in range [%d, %d]", name, lower, upper))
+	}
+	return n, nil
+}
+
+// parseSort parses comma separated sort fields (ex. "name,-createdAt")
+func parseSort(val string, allowed []string) ([]SortField, error) {
+	var res []SortField
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		s := SortField{}
+		switch {
+		case strings.HasPrefix(item, sortDescPrefix):
+			s.Desc = true
+			item = strings.TrimPrefix(item, sortDescPrefix)
+		case strings.HasPrefix(item, sortAscPrefix):
+			item = strings.TrimPrefix(item, sortAscPrefix)
+		}
+		if !contains(allowed, item) {
+			return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("sorting by field %q is not allowed", item))
+		}
+		for _, prev := range res {
+			if prev.Field == item {
+				return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+					fmt.Sprintf("duplicate sort field %q", item))
+			}
+		}
+		s.Field = item
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// parseFields parses comma separated list of returned fields
+func parseFields(val string, allowed []string) ([]string, error) {
+	var res []string
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if !contains(allowed, item) {
+			return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("field %q is not allowed", item))
+		}
+		if !contains(res, item) {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestParseListQuery(t *testing.T) {
+	cfg := api.ListQueryConfig{
+		DefaultLimit: 10,
+		MaxLimit:     100,
+		SortFields:   []string{"name", "createdAt"},
+		Fields:       []string{"name", "size"},
+	}
+	newContext := func(target string) echo.Context {
+		return echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+	}
+
+	t.Run("should use defaults", func(t *testing.T) {
+		q, err := api.ParseListQuery(newContext("/items"), cfg)
+		if err != nil {
+			t.Fatalf("ParseListQuery returned error: %v", err)
+		}
+		if q.Limit != 10 || q.Offset != 0 || q.Sort != nil || q.Fields != nil {
+			t.Errorf("got %+v, expected default query", q)
+		}
+	})
+	t.Run("should parse and convert to find options", func(t *testing.T) {
+		q, err := api.ParseListQuery(newContext("/items?limit=20&offset=40&sort=-createdAt,name&fields=name"), cfg)
+		if err != nil {
+			t.Fatalf("ParseListQuery returned error: %v", err)
+		}
+		opts := q.FindOptions()
+		if *opts.Limit != 20 || *opts.Skip != 40 {
+			t.Errorf("got limit %d skip %d, expected 20 40", *opts.Limit, *opts.Skip)
+		}
+		sort := opts.Sort.(bson.D)
+		if len(sort) != 2 || sort[0].Key != "createdAt" || sort[0].Value != -1 || sort[1].Value != 1 {
+			t.Errorf("got sort %v", sort)
+		}
+		projection := opts.Projection.(bson.D)
+		if len(projection) != 1 || projection[0].Key != "name" {
+			t.Errorf("got projection %v", projection)
+		}
+	})
+	t.Run("should reject invalid parameters", func(t *testing.T) {
+		tests := []string{
+			"/items?limit=0",
+			"/items?limit=101",
+			"/items?limit=abc",
+			"/items?offset=-1",
+			"/items?sort=size",
+			"/items?sort=name,-name",
+			"/items?fields=secret",
+		}
+		for _, target := range tests {
+			_, err := api.ParseListQuery(newContext(target), cfg)
+			if err == nil {
+				t.Errorf("%s: expected error", target)
+				continue
+			}
+			if code := apperrors.ToAppError(err).ErrorCode; code != apperrors.ErrorDataValidation {
+				t.Errorf("%s: got error code %s, expected %s", target, code, apperrors.ErrorDataValidation)
+			}
+		}
+	})
+}
+
+func TestNewPage(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.GET("/items", func(c echo.Context) error {
+		q, err := api.ParseListQuery(c, api.ListQueryConfig{DefaultLimit: 2})
+		if err != nil {
+			return err
+		}
+		items := []string{"a", "b", "c", "d", "e"}
+		end := q.Offset + q.Limit
+		if end > int64(len(items)) {
+			end = int64(len(items))
+		}
+		return c.JSON(http.StatusOK, api.NewPage(c, q, items[q.Offset:end], int64(len(items))))
+	})
+	get := func(t *testing.T, target string) (int, api.Page[string]) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var page api.Page[string]
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("json.Unmarshal returned error: %v", err)
+			}
+		}
+		return rec.Code, page
+	}
+
+	t.Run("should return link to the next page", func(t *testing.T) {
+		_, page := get(t, "/items?offset=1")
+		if len(page.Items) != 2 || page.Total != 5 || page.Offset != 1 {
+			t.Errorf("got %+v", page)
+		}
+		if page.Next != "/items?limit=2&offset=3" {
+			t.Errorf("got next link %s", page.Next)
+		}
+	})
+	t.Run("should not return next link on the last page", func(t *testing.T) {
+		_, page := get(t, "/items?offset=3")
+		if page.Next != "" {
+			t.Errorf("got next link %s, expected empty", page.Next)
+		}
+	})
+	t.Run("should respond with bad request on invalid parameters", func(t *testing.T) {
+		if code, _ := get(t, "/items?limit=-1"); code != http.StatusBadRequest {
+			t.Errorf("got status %d, expected %d", code, http.StatusBadRequest)
+		}
+	})
+}
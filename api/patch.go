@@ -0,0 +1,366 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// MIMEApplicationMergePatch is content type of JSON Merge Patch (RFC 7386)
+	MIMEApplicationMergePatch = "application/merge-patch+json"
+	// MIMEApplicationJSONPatch is content type of JSON Patch (RFC 6902)
+	MIMEApplicationJSONPatch = "application/json-patch+json"
+
+	patchPathSeparator = "."
+	patchAppendIndex   = "-"
+)
+
+// PatchType is type of mutable field
+type PatchType int
+
+const (
+	// PatchString is string field
+	PatchString PatchType = iota
+	// PatchInt is integer field
+	PatchInt
+	// PatchFloat is floating point number field
+	PatchFloat
+	// PatchBool is boolean field
+	PatchBool
+	// PatchTime is date/time field (RFC3339 string in patch)
+	PatchTime
+	// PatchStrings is list of strings field (JSON Patch can append items with "/field/-" path)
+	PatchStrings
+)
+
+// PatchField is a field allowed to be modified by patch
+type PatchField struct {
+	// Type of field value
+	Type PatchType
+	// Field is database field name (patch path is used if empty)
+	Field string
+	// Removable allows removing field (null value of merge patch or remove operation of JSON Patch)
+	Removable bool
+}
+
+// PatchSchema is allow-list of mutable fields of resource,
+// nested fields are defined using dot notation (ex. "meta.color")
+type PatchSchema map[string]PatchField
+
+// JSONPatchOperation is an operation of JSON Patch document
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ParsePatch reads JSON Merge Patch or JSON Patch request body (depends on content type)
+// and converts it to mongo update document
+func ParsePatch(ctx echo.Context, schema PatchSchema) (bson.D, error) {
+	mediaType, _, err := mime.ParseMediaType(GetContentType(ctx))
+	if err != nil || (mediaType != MIMEApplicationMergePatch && mediaType != MIMEApplicationJSONPatch) {
+		return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("unsupported content type %q, expected %s or %s",
+				GetContentType(ctx), MIMEApplicationMergePatch, MIMEApplicationJSONPatch))
+	}
+	if GetContentSize(ctx) > DefaultMaxBodySize {
+		return nil, errPayloadTooLarge(DefaultMaxBodySize)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Response(), ctx.Request().Body, DefaultMaxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errPayloadTooLarge(DefaultMaxBodySize)
+		}
+		return nil, apperrors.CreateError(apperrors.ErrorDataValidation, "failed to read request body", err)
+	}
+	if mediaType == MIMEApplicationMergePatch {
+		return schema.MergePatchUpdate(body)
+	}
+	return schema.JSONPatchUpdate(body)
+}
+
+// MergePatchUpdate converts JSON Merge Patch document to mongo update document
+func (s PatchSchema) MergePatchUpdate(patch []byte) (bson.D, error) {
+	var doc map[string]interface{}
+	if err := decodePatch(patch, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrorDataValidation, "merge patch should be JSON object")
+	}
+	u := newPatchUpdate(s)
+	u.merge("", doc)
+	return u.result()
+}
+
+// JSONPatchUpdate converts JSON Patch document to mongo update document
+// (supported operations are add, replace and remove)
+func (s PatchSchema) JSONPatchUpdate(patch []byte) (bson.D, error) {
+	var ops []JSONPatchOperation
+	if err := decodePatch(patch, &ops); err != nil {
+		return nil, err
+	}
+	u := newPatchUpdate(s)
+	for _, op := range ops {
+		path, appendItem, err := pointerToPath(op.Path)
+		if err != nil {
+			u.fail(op.Path, err.Error())
+			continue
+		}
+		switch op.Op {
+		case "add", "replace":
+			var val interface{}
+			if len(op.Value) == 0 {
+				u.fail(op.Path, "value is required")
+				continue
+			}
+			if err = decodePatch(op.Value, &val); err != nil {
+				u.fail(op.Path, "value is not valid JSON")
+				continue
+			}
+			if appendItem {
+				u.push(path, val)
+			} else {
+				u.set(path, val)
+			}
+		case "remove":
+			if appendItem {
+				u.fail(op.Path, "array item can not be removed")
+				continue
+			}
+			u.unset(path)
+		default:
+			u.fail(op.Path, fmt.Sprintf("operation %q is not supported", op.Op))
+		}
+	}
+	return u.result()
+}
+
+// decodePatch decodes JSON keeping numbers as json.Number
+func decodePatch(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return apperrors.CreateError(apperrors.ErrorDataValidation, "patch is not valid JSON", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation, "patch should contain a single JSON value")
+	}
+	return nil
+}
+
+// pointerToPath converts JSON pointer (RFC 6901) to dot notation path,
+// it reports if pointer references end of array ("/field/-")
+func pointerToPath(pointer string) (string, bool, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", false, fmt.Errorf("path should start with \"/\"")
+	}
+	segments := strings.Split(pointer[1:], "/")
+	appendItem := false
+	if len(segments) > 1 && segments[len(segments)-1] == patchAppendIndex {
+		appendItem = true
+		segments = segments[:len(segments)-1]
+	}
+	for i, seg := range segments {
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		if seg == "" || strings.Contains(seg, patchPathSeparator) {
+			return "", false, fmt.Errorf("path is not allowed")
+		}
+		segments[i] = seg
+	}
+	return strings.Join(segments, patchPathSeparator), appendItem, nil
+}
+
+// patchUpdate accumulates mongo update operators
+type patchUpdate struct {
+	schema   PatchSchema
+	setDoc   bson.D
+	unsetDoc bson.D
+	pushDoc  bson.D
+	touched  map[string]bool
+	details  []apperrors.FieldError
+}
+
+func newPatchUpdate(schema PatchSchema) *patchUpdate {
+	return &patchUpdate{schema: schema, touched: make(map[string]bool)}
+}
+
+// merge applies merge patch object on path
+func (u *patchUpdate) merge(prefix string, doc map[string]interface{}) {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + patchPathSeparator + key
+		}
+		if key == "" || strings.Contains(key, patchPathSeparator) {
+			u.fail(path, "path is not allowed")
+			continue
+		}
+		val := doc[key]
+		if nested, ok := val.(map[string]interface{}); ok {
+			if _, found := u.schema[path]; !found {
+				u.merge(path, nested)
+				continue
+			}
+		}
+		if val == nil {
+			u.unset(path)
+		} else {
+			u.set(path, val)
+		}
+	}
+}
+
+func (u *patchUpdate) set(path string, val interface{}) {
+	field, ok := u.field(path)
+	if !ok {
+		return
+	}
+	v, err := field.value(val)
+	if err != nil {
+		u.fail(path, err.Error())
+		return
+	}
+	u.setDoc = append(u.setDoc, bson.E{Key: field.Field, Value: v})
+}
+
+func (u *patchUpdate) push(path string, val interface{}) {
+	field, ok := u.field(path)
+	if !ok {
+		return
+	}
+	if field.Type != PatchStrings {
+		u.fail(path, "field is not a list")
+		return
+	}
+	s, isString := val.(string)
+	if !isString {
+		u.fail(path, "list item should be string")
+		return
+	}
+	u.pushDoc = append(u.pushDoc, bson.E{Key: field.Field, Value: s})
+}
+
+func (u *patchUpdate) unset(path string) {
+	field, ok := u.field(path)
+	if !ok {
+		return
+	}
+	if !field.Removable {
+		u.fail(path, "field can not be removed")
+		return
+	}
+	u.unsetDoc = append(u.unsetDoc, bson.E{Key: field.Field, Value: ""})
+}
+
+// field returns schema of path, it returns false if path is not mutable or already modified
+func (u *patchUpdate) field(path string) (PatchField, bool) {
+	field, found := u.schema[path]
+	if !found {
+		u.fail(path, "field is not mutable")
+		return field, false
+	}
+	if u.touched[path] {
+		u.fail(path, "field is modified more than once")
+		return field, false
+	}
+	u.touched[path] = true
+	if field.Field == "" {
+		field.Field = path
+	}
+	return field, true
+}
+
+func (u *patchUpdate) fail(path string, msg string) {
+	u.details = append(u.details, apperrors.FieldError{Field: path, Message: msg})
+}
+
+func (u *patchUpdate) result() (bson.D, error) {
+	if len(u.details) > 0 {
+		return nil, apperrors.NewValidationError("invalid patch", u.details)
+	}
+	update := bson.D{}
+	if len(u.setDoc) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: u.setDoc})
+	}
+	if len(u.unsetDoc) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: u.unsetDoc})
+	}
+	if len(u.pushDoc) > 0 {
+		update = append(update, bson.E{Key: "$push", Value: u.pushDoc})
+	}
+	if len(update) == 0 {
+		return nil, apperrors.NewAppError(apperrors.ErrorDataValidation, "patch contains no changes")
+	}
+	return update, nil
+}
+
+// value converts JSON value to field type
+func (f PatchField) value(val interface{}) (interface{}, error) {
+	switch f.Type {
+	case PatchString:
+		if s, ok := val.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("should be string")
+	case PatchInt:
+		if n, ok := val.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("should be integer")
+	case PatchFloat:
+		if n, ok := val.(json.Number); ok {
+			if fl, err := n.Float64(); err == nil {
+				return fl, nil
+			}
+		}
+		return nil, fmt.Errorf("should be number")
+	case PatchBool:
+		if b, ok := val.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("should be boolean")
+	case PatchTime:
+		if s, ok := val.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("should be RFC3339 date-time")
+	case PatchStrings:
+		if items, ok := val.([]interface{}); ok {
+			res := make([]string, 0, len(items))
+			for _, item := range items {
+				s, isString := item.(string)
+				if !isString {
+					return nil, fmt.Errorf("should be list of strings")
+				}
+				res = append(res, s)
+			}
+			return res, nil
+		}
+		return nil, fmt.Errorf("should be list of strings")
+	}
+	return nil, fmt.Errorf("unsupported field type")
+}
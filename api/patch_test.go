@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+func TestParsePatch(t *testing.T) {
+	schema := api.PatchSchema{
+		"name":       {Type: api.PatchString},
+		"size":       {Type: api.PatchInt},
+		"tags":       {Type: api.PatchStrings, Removable: true},
+		"meta.color": {Type: api.PatchString, Field: "metadata.color", Removable: true},
+	}
+	parse := func(t *testing.T, contentType, body string) (string, error) {
+		req := httptest.NewRequest(http.MethodPatch, "/items/1", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+		update, err := api.ParsePatch(ctx, schema)
+		if err != nil {
+			return "", err
+		}
+		res, err := bson.MarshalExtJSON(update, false, false)
+		if err != nil {
+			t.Fatalf("bson.MarshalExtJSON returned error: %v", err)
+		}
+		return string(res), nil
+	}
+	details := func(err error) []apperrors.FieldError {
+		return apperrors.ToAppError(err).Details
+	}
+
+	t.Run("should convert merge patch to update document", func(t *testing.T) {
+		got, err := parse(t, api.MIMEApplicationMergePatch, `{"name":"fw","size":10,"meta":{"color":null},"tags":["a"]}`)
+		if err != nil {
+			t.Fatalf("ParsePatch returned error: %v", err)
+		}
+		expected := `{"$set":{"name":"fw","size":10,"tags":["a"]},"$unset":{"metadata.color":""}}`
+		if got != expected {
+			t.Errorf("got %s, expected %s", got, expected)
+		}
+	})
+	t.Run("should convert json patch to update document", func(t *testing.T) {
+		body := `[{"op":"replace","path":"/meta/color","value":"red"},{"op":"add","path":"/tags/-","value":"b"},{"op":"replace","path":"/size","value":1}]`
+		got, err := parse(t, api.MIMEApplicationJSONPatch, body)
+		if err != nil {
+			t.Fatalf("ParsePatch returned error: %v", err)
+		}
+		expected := `{"$set":{"metadata.color":"red","size":1},"$push":{"tags":"b"}}`
+		if got != expected {
+			t.Errorf("got %s, expected %s", got, expected)
+		}
+	})
+	t.Run("should reject not mutable and invalid fields", func(t *testing.T) {
+		_, err := parse(t, api.MIMEApplicationMergePatch, `{"$set":{"admin":true},"name":1,"size":null,"meta":{"owner":"x"}}`)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		var fields []string
+		for _, d := range details(err) {
+			fields = append(fields, d.Field)
+		}
+		if strings.Join(fields, ",") != "$set.admin,meta.owner,name,size" {
+			t.Errorf("got invalid fields %v", fields)
+		}
+	})
+	t.Run("should reject invalid json patch operations", func(t *testing.T) {
+		tests := []string{
+			`[{"op":"move","path":"/name","from":"/size"}]`,
+			`[{"op":"add","path":"name","value":"x"}]`,
+			`[{"op":"add","path":"/name"}]`,
+			`[{"op":"add","path":"/size/-","value":"x"}]`,
+			`[{"op":"replace","path":"/name","value":"a"},{"op":"replace","path":"/name","value":"b"}]`,
+			`[{"op":"remove","path":"/name"}]`,
+			`[]`,
+			`{}`,
+		}
+		for _, body := range tests {
+			_, err := parse(t, api.MIMEApplicationJSONPatch, body)
+			if err == nil {
+				t.Errorf("%s: expected error", body)
+				continue
+			}
+			if code := apperrors.ToAppError(err).ErrorCode; code != apperrors.ErrorDataValidation {
+				t.Errorf("%s: got error code %s, expected %s", body, code, apperrors.ErrorDataValidation)
+			}
+		}
+	})
+	t.Run("should reject unsupported content type", func(t *testing.T) {
+		if _, err := parse(t, echo.MIMEApplicationJSON, `{"name":"fw"}`); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("should produce details serializable in error response", func(t *testing.T) {
+		_, err := parse(t, api.MIMEApplicationMergePatch, `{"size":"x"}`)
+		b, _ := json.Marshal(api.NewErrorResponse(context.Background(), http.StatusBadRequest, err))
+		if !strings.Contains(string(b), `"details":[{"field":"size","message":"should be integer"}]`) {
+			t.Errorf("got %s", b)
+		}
+	})
+}
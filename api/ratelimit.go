@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm is rate limiting algorithm
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts up to bucket capacity refilled with constant rate
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow limits number of requests in sliding window
+	// (approximated by weighted counters of current and previous fixed windows)
+	SlidingWindow
+)
+
+// RateLimit is allowed rate of requests
+type RateLimit struct {
+	// Limit is number of requests allowed in Window
+	Limit int64
+	// Window is duration of rate limit window
+	Window time.Duration
+	// Burst is token bucket capacity (Limit if 0)
+	Burst int64
+}
+
+// RateLimitResult is result of rate limit check
+type RateLimitResult struct {
+	// Allowed reports if request is allowed
+	Allowed bool
+	// Limit is request quota
+	Limit int64
+	// Remaining is remaining request quota
+	Remaining int64
+	// Reset is time until quota is fully restored
+	Reset time.Duration
+	// RetryAfter is time until next request is allowed (only for rejected request)
+	RetryAfter time.Duration
+}
+
+// RateLimitState is stored state of rate limiter key
+type RateLimitState struct {
+	// Tokens is number of available tokens of token bucket
+	Tokens float64 `bson:"tokens"`
+	// WindowStart is start time of current sliding window
+	WindowStart time.Time `bson:"windowStart"`
+	// Count is number of requests in current window
+	Count int64 `bson:"count"`
+	// PrevCount is number of requests in previous window
+	PrevCount int64 `bson:"prevCount"`
+	// Updated is time of last update
+	Updated time.Time `bson:"updated"`
+}
+
+// RateLimitStore is storage of rate limiter state
+type RateLimitStore interface {
+	// Take atomically applies algorithm to state of key consuming one request if it is allowed,
+	// it returns updated state and reports if request was allowed (state is kept at least ttl after update)
+	Take(ctx context.Context, key string, algorithm RateLimitAlgorithm, limit RateLimit, now time.Time, ttl time.Duration) (RateLimitState, bool, error)
+}
+
+// RateLimiter checks request rate using algorithm and state stored in RateLimitStore
+type RateLimiter struct {
+	store     RateLimitStore
+	algorithm RateLimitAlgorithm
+}
+
+// NewRateLimiter creates new RateLimiter
+func NewRateLimiter(store RateLimitStore, algorithm RateLimitAlgorithm) *RateLimiter {
+	return &RateLimiter{store: store, algorithm: algorithm}
+}
+
+// Allow consumes one request of key quota
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Limit
+	}
+	state, allowed, err := l.store.Take(ctx, key, l.algorithm, limit, now, 2*limit.Window)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if l.algorithm == SlidingWindow {
+		return slidingWindowResult(state, allowed, limit, now), nil
+	}
+	return tokenBucketResult(state, allowed, limit), nil
+}
+
+// ApplyRateLimit applies algorithm to state of key (nil for new key) consuming one request if it is allowed,
+// it returns updated state and reports if request was allowed (for RateLimitStore implementations)
+func ApplyRateLimit(algorithm RateLimitAlgorithm, state *RateLimitState, limit RateLimit, now time.Time) (RateLimitState, bool) {
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Limit
+	}
+	if algorithm == SlidingWindow {
+		return slidingWindow(state, limit, now)
+	}
+	return tokenBucket(state, limit, now)
+}
+
+// tokenBucket applies token bucket algorithm
+func tokenBucket(state *RateLimitState, limit RateLimit, now time.Time) (RateLimitState, bool) {
+	capacity := float64(limit.Burst)
+	rate := float64(limit.Limit) / limit.Window.Seconds()
+	tokens := capacity
+	if state != nil {
+		elapsed := now.Sub(state.Updated).Seconds()
+		tokens = math.Min(capacity, state.Tokens+math.Max(elapsed, 0)*rate)
+	}
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return RateLimitState{Tokens: tokens, Updated: now}, allowed
+}
+
+// tokenBucketResult returns result of token bucket check from updated state
+func tokenBucketResult(state RateLimitState, allowed bool, limit RateLimit) RateLimitResult {
+	capacity := float64(limit.Burst)
+	rate := float64(limit.Limit) / limit.Window.Seconds()
+	res := RateLimitResult{Allowed: allowed, Limit: limit.Burst}
+	if !allowed {
+		res.RetryAfter = rateDuration((1 - state.Tokens) / rate)
+	}
+	res.Remaining = int64(math.Floor(state.Tokens))
+	res.Reset = rateDuration((capacity - state.Tokens) / rate)
+	return res
+}
+
+// slidingWindow applies sliding window algorithm
+func slidingWindow(state *RateLimitState, limit RateLimit, now time.Time) (RateLimitState, bool) {
+	windowStart := now.Truncate(limit.Window)
+	next := RateLimitState{WindowStart: windowStart, Updated: now}
+	if state != nil {
+		switch {
+		case state.WindowStart.Equal(windowStart):
+			next.Count = state.Count
+			next.PrevCount = state.PrevCount
+		case state.WindowStart.Equal(windowStart.Add(-limit.Window)):
+			next.PrevCount = state.Count
+		}
+	}
+	weight := 1 - now.Sub(windowStart).Seconds()/limit.Window.Seconds()
+	allowed := float64(next.PrevCount)*weight+float64(next.Count)+1 <= float64(limit.Limit)
+	if allowed {
+		next.Count++
+	}
+	return next, allowed
+}
+
+// slidingWindowResult returns result of sliding window check from updated state
+func slidingWindowResult(state RateLimitState, allowed bool, limit RateLimit, now time.Time) RateLimitResult {
+	elapsed := now.Sub(state.WindowStart)
+	weight := 1 - elapsed.Seconds()/limit.Window.Seconds()
+	estimated := float64(state.PrevCount)*weight + float64(state.Count)
+
+	res := RateLimitResult{Allowed: allowed, Limit: limit.Limit, Reset: limit.Window - elapsed}
+	if !allowed {
+		res.RetryAfter = res.Reset
+		if state.PrevCount > 0 && float64(state.Count+1) <= float64(limit.Limit) {
+			// previous window weight should decrease enough to fit one more request
+			fit := 1 - float64(limit.Limit-state.Count-1)/float64(state.PrevCount)
+			res.RetryAfter = rateDuration(fit*limit.Window.Seconds() - elapsed.Seconds())
+		}
+	}
+	res.Remaining = int64(math.Max(0, float64(limit.Limit)-math.Ceil(estimated)))
+	return res
+}
+
+// rateDuration converts seconds to duration rounded up to milliseconds
+// (ignoring floating point errors)
+func rateDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds*1000-1e-6)) * time.Millisecond
+}
+
+// MemoryRateLimitStore is in-memory RateLimitStore (for tests and single instance services)
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryRateLimitEntry
+	cleaned time.Time
+}
+
+type memoryRateLimitEntry struct {
+	state     RateLimitState
+	expiresAt time.Time
+}
+
+// NewMemoryRateLimitStore creates new MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: make(map[string]memoryRateLimitEntry)}
+}
+
+// Take atomically applies algorithm to state of key consuming one request if it is allowed
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, algorithm RateLimitAlgorithm, limit RateLimit, now time.Time, ttl time.Duration) (RateLimitState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.cleaned) > ttl {
+		for k, e := range s.entries {
+			if e.expiresAt.Before(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.cleaned = now
+	}
+	var state *RateLimitState
+	if e, found := s.entries[key]; found {
+		state = &e.state
+	}
+	next, allowed := ApplyRateLimit(algorithm, state, limit, now)
+	s.entries[key] = memoryRateLimitEntry{state: next, expiresAt: now.Add(ttl)}
+	return next, allowed, nil
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/db"
+)
+
+// DefaultPublicAddress is default address of public listener
+const DefaultPublicAddress = ":8080"
+
+// ServerOption configures Server
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	publicAddr  string
+	adminAddr   string
+	lifecycle   LifecycleConfig
+	monitor     HealthMonitorConfig
+	metrics     MetricsConfig
+	checks      []HealthCheck
+	hooks       []namedWarmupHook
+	repos       []db.BaseRepository
+	middlewares []echo.MiddlewareFunc
+	admin       *AdminConfig
+	concurrency *ConcurrencyConfig
+	timeout     *TimeoutConfig
+	openapi     *OpenAPIConfig
+}
+
+// WithPublicAddress sets address of public listener (DefaultPublicAddress if not set)
+func WithPublicAddress(addr string) ServerOption {
+	return func(o *serverOptions) {
+		o.publicAddr = addr
+	}
+}
+
+// WithAdminAddress sets address of admin listener serving health and metrics endpoints,
+// if not set admin endpoints are served by public listener
+func WithAdminAddress(addr string) ServerOption {
+	return func(o *serverOptions) {
+		o.adminAddr = addr
+	}
+}
+
+// WithLifecycleConfig sets configuration of startup and graceful shutdown
+func WithLifecycleConfig(cfg LifecycleConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.lifecycle = cfg
+	}
+}
+
+// WithHealthMonitorConfig sets configuration of background health monitor
+func WithHealthMonitorConfig(cfg HealthMonitorConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.monitor = cfg
+	}
+}
+
+// WithMetricsConfig sets configuration of http server metrics
+func WithMetricsConfig(cfg MetricsConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.metrics = cfg
+	}
+}
+
+// WithHealthCheck adds readiness checks
+func WithHealthCheck(checks ...HealthCheck) ServerOption {
+	return func(o *serverOptions) {
+		o.checks = append(o.checks, checks...)
+	}
+}
+
+// WithWarmupHook adds hook which should complete before startup probe passes
+func WithWarmupHook(name string, hook WarmupHook) ServerOption {
+	return func(o *serverOptions) {
+		o.hooks = append(o.hooks, namedWarmupHook{name: name, hook: hook})
+	}
+}
+
+// WithRepository adds repository checked by readiness probe and disconnected on shutdown
+func WithRepository(name string, repo db.BaseRepository) ServerOption {
+	return func(o *serverOptions) {
+		o.repos = append(o.repos, repo)
+		o.checks = append(o.checks, HealthCheck{
+			Name: name,
+			Check: func(ctx context.Context) HealthEntryStatus {
+				if err := repo.Ping(ctx); err != nil {
+					return HealthEntryStatus{Status: StatusUnhealthy, Data: err.Error()}
+				}
+				return HealthEntryStatus{Status: StatusHealthy}
+			},
+		})
+	}
+}
+
+// WithMiddleware adds service middlewares executed after standard ones
+func WithMiddleware(mw ...echo.MiddlewareFunc) ServerOption {
+	return func(o *serverOptions) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
+
+// WithConcurrencyLimit enables limiting of in-flight requests of public listener,
+// current limit is reported by readiness check and metrics
+func WithConcurrencyLimit(cfg ConcurrencyConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.concurrency = &cfg
+	}
+}
+
+// WithRequestTimeout enables deadline of request handling on public listener
+// (timed out requests are reported to concurrency limiter as dropped)
+func WithRequestTimeout(cfg TimeoutConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.timeout = &cfg
+	}
+}
+
+// WithOpenAPI enables OpenAPIPath endpoint of public listener serving OpenAPI document of public routes,
+// name and version of server are used if not set in config
+func WithOpenAPI(cfg OpenAPIConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.openapi = &cfg
+	}
+}
+
+// WithAdminRoutes enables admin endpoints on admin listener under AdminPath,
+// name, version and root logger of server are used if not set in config.
+// NewServer fails if admin address is not set unless AdminConfig.AllowPublic is set
+func WithAdminRoutes(cfg AdminConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.admin = &cfg
+	}
+}
+
+// Server is OTA service http server with common middlewares, health endpoints and graceful shutdown
+type Server struct {
+	// Public is echo instance serving service API
+	Public *echo.Echo
+	// Admin is echo instance serving health and metrics endpoints (the same as Public if admin address is not set)
+	Admin *echo.Echo
+	// Health is registry of readiness checks
+	Health *HealthRegistry
+	// Monitor runs readiness checks in background
+	Monitor *HealthMonitor
+	// Metrics is http server metrics
+	Metrics *Metrics
+	// Lifecycle controls startup and graceful shutdown
+	Lifecycle *Lifecycle
+	// Limiter limits in-flight requests (nil if concurrency limit is not enabled)
+	Limiter *ConcurrencyLimiter
+	// OpenAPI describes public routes (nil if OpenAPI document is not enabled)
+	OpenAPI *OpenAPI
+
+	log        logger.Logger
+	publicAddr string
+	adminAddr  string
+}
+
+// NewServer creates Server with standard middleware order:
+// Recover, Tracing, RequestID, ServerHeader, Metrics, concurrency limiter, request timeout and service middlewares
+func NewServer(name, version string, lgr logger.Logger, opts ...ServerOption) (*Server, error) {
+	o := serverOptions{
+		publicAddr: DefaultPublicAddress,
+		metrics:    DefaultMetricsConfig(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	metrics, err := NewMetrics(o.metrics)
+	if err != nil {
+		return nil, apperrors.CreateError(apperrors.ErrorGeneric, "failed to create metrics", err)
+	}
+	lc := NewLifecycle(lgr, o.lifecycle)
+	lc.AddRepository(o.repos...)
+	for _, h := range o.hooks {
+		lc.AddWarmupHook(h.name, h.hook)
+	}
+	checks := append([]HealthCheck{lc.ReadinessCheck()}, o.checks...)
+	var limiter *ConcurrencyLimiter
+	if o.concurrency != nil {
+		cfg := *o.concurrency
+		if cfg.Prefix == "" {
+			cfg.Prefix = o.metrics.Prefix
+		}
+		limiter = NewConcurrencyLimiter(cfg)
+		if err = metrics.Register(limiter.Collectors()...); err != nil {
+			return nil, apperrors.CreateError(apperrors.ErrorGeneric, "failed to register limiter metrics", err)
+		}
+		checks = append(checks, limiter.ReadinessCheck())
+	}
+	reg := NewHealthRegistry()
+	if err = reg.Register(checks...); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Public:     newEcho(),
+		Health:     reg,
+		Monitor:    NewHealthMonitor(reg, o.monitor),
+		Metrics:    metrics,
+		Lifecycle:  lc,
+		Limiter:    limiter,
+		log:        lgr.SetOperation("Server"),
+		publicAddr: o.publicAddr,
+		adminAddr:  o.adminAddr,
+	}
+	s.Admin = s.Public
+	if o.adminAddr != "" {
+		s.Admin = newEcho()
+		s.Admin.Use(middleware.Recover())
+	}
+
+	s.Public.Use(
+		middleware.Recover(),
+		Tracing(),
+		RequestID(),
+		ServerHeader(name, version),
+		metrics.Middleware(),
+	)
+	if limiter != nil {
+		s.Public.Use(limiter.Middleware())
+	}
+	if o.timeout != nil {
+		s.Public.Use(Timeout(*o.timeout))
+	}
+	s.Public.Use(o.middlewares...)
+
+	s.Admin.GET(LivenessPath, HealthzHandler)
+	s.Admin.GET(HealthHistoryPath, HealthHistoryHandler(s.Monitor))
+	s.Admin.GET(ReadinessPath, LifecycleReadinessHandler(lc, s.Monitor))
+	if o.openapi != nil {
+		cfg := *o.openapi
+		if cfg.Title == "" {
+			cfg.Title, cfg.Version = name, version
+		}
+		s.OpenAPI = NewOpenAPI(cfg)
+		s.OpenAPI.Register(s.Public)
+		health := map[int]interface{}{
+			http.StatusOK:                 HealthStatusResponse{},
+			http.StatusServiceUnavailable: HealthStatusResponse{},
+		}
+		s.OpenAPI.DescribeRoute(http.MethodGet, LivenessPath, RouteDoc{Summary: "Liveness probe", Tags: []string{"health"}})
+		s.OpenAPI.DescribeRoute(http.MethodGet, ReadinessPath, RouteDoc{Summary: "Readiness probe", Tags: []string{"health"}, Responses: health})
+		s.OpenAPI.DescribeRoute(http.MethodGet, StartupPath, RouteDoc{Summary: "Startup probe", Tags: []string{"health"}})
+	}
+	s.Admin.GET(fmt.Sprintf("%s/:%s", ReadinessPath, HealthCheckParam), HealthCheckHandler(reg))
+	s.Admin.GET(StartupPath, StartupzHandler(lc))
+	s.Admin.GET(MetricsPath, MetricsHandler(metrics))
+	if o.admin != nil {
+		cfg := *o.admin
+		if cfg.Name == "" {
+			cfg.Name, cfg.Version = name, version
+		}
+		if cfg.Logger == nil {
+			cfg.Logger = lgr
+		}
+		if s.Admin == s.Public && !cfg.AllowPublic {
+			return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+				"admin endpoints require admin listener address (set AdminConfig.AllowPublic to serve them on public listener)")
+		}
+		if err = RegisterAdminRoutes(s.Admin.Group(AdminPath), cfg); err != nil {
+			return nil, err
+		}
+		if s.OpenAPI != nil {
+			// admin endpoints are not part of public API
+			for _, r := range s.Admin.Routes() {
+				if strings.HasPrefix(r.Path, AdminPath+"/") {
+					s.OpenAPI.DescribeRoute(r.Method, r.Path, RouteDoc{Hidden: true})
+				}
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Run starts listeners, health monitor and warm-up hooks,
+// blocks until termination signal or ctx cancellation and gracefully shuts down
+func (s *Server) Run(ctx context.Context) error {
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var (
+		mu     sync.Mutex
+		runErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if runErr == nil {
+			runErr = err
+		}
+		mu.Unlock()
+		stop()
+	}
+
+	servers := []Shutdowner{s.Public}
+	s.start(s.Public, s.publicAddr, fail)
+	if s.Admin != s.Public {
+		servers = append(servers, s.Admin)
+		s.start(s.Admin, s.adminAddr, fail)
+	}
+	go s.Monitor.Run(runCtx)
+	go func() {
+		if err := s.Lifecycle.Warmup(runCtx); err != nil {
+			fail(err)
+		}
+	}()
+
+	err := s.Lifecycle.Run(runCtx, servers...)
+	mu.Lock()
+	defer mu.Unlock()
+	if runErr != nil {
+		return runErr
+	}
+	return err
+}
+
+func (s *Server) start(e *echo.Echo, addr string, fail func(error)) {
+	go func() {
+		s.log.WithField("address", addr).Info("Listener started")
+		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fail(apperrors.CreateErrorAndLogIt(s.log,
+				apperrors.ErrorGeneric,
+				fmt.Sprintf("Listener %s failed", addr), err))
+		}
+	}()
+}
+
+func newEcho() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.HTTPErrorHandler = NewErrorHandler().Handler
+	return e
+}
@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+)
+
+func TestServer(t *testing.T) {
+	newServer := func(t *testing.T, opts ...api.ServerOption) *api.Server {
+		opts = append(opts,
+			api.WithPublicAddress("127.0.0.1:0"),
+			api.WithLifecycleConfig(api.LifecycleConfig{DrainPeriod: -1}))
+		s, err := api.NewServer("svc", "1.0.0", logger.NewNopLogger(), opts...)
+		if err != nil {
+			t.Fatalf("NewServer returned error: %v", err)
+		}
+		return s
+	}
+	get := func(e *echo.Echo, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("should serve health and metrics endpoints on admin listener", func(t *testing.T) {
+		s := newServer(t, api.WithAdminAddress("127.0.0.1:0"))
+		for _, path := range []string{api.LivenessPath, api.HealthHistoryPath, api.MetricsPath} {
+			if rec := get(s.Admin, path); rec.Code != http.StatusOK {
+				t.Errorf("%s: got %d, expected %d", path, rec.Code, http.StatusOK)
+			}
+			if rec := get(s.Public, path); rec.Code != http.StatusNotFound {
+				t.Errorf("%s should not be served by public listener", path)
+			}
+		}
+		if rec := get(s.Admin, api.StartupPath); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("startup probe should fail before warm-up, got %d", rec.Code)
+		}
+	})
+	t.Run("should add standard headers to public responses", func(t *testing.T) {
+		s := newServer(t)
+		s.Public.GET("/ping", func(c echo.Context) error {
+			return c.String(http.StatusOK, "pong")
+		})
+		rec := get(s.Public, "/ping")
+		if got := rec.Header().Get(echo.HeaderServer); got != "svc/1.0.0" {
+			t.Errorf("got Server header %q, expected svc/1.0.0", got)
+		}
+		if rec.Header().Get(echo.HeaderXRequestID) == "" {
+			t.Error("response should have request id header")
+		}
+	})
+	t.Run("should serve OpenAPI document of public routes", func(t *testing.T) {
+		s := newServer(t, api.WithOpenAPI(api.OpenAPIConfig{}))
+		rec := get(s.Public, api.OpenAPIPath)
+		var doc api.OpenAPIDocument
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, error %v", rec.Code, err)
+		}
+		if doc.Info.Title != "svc" || doc.Info.Version != "1.0.0" {
+			t.Errorf("got info %+v", doc.Info)
+		}
+		ready := doc.Paths[api.ReadinessPath]["get"]
+		if ready == nil || ready.Responses["503"].Content[echo.MIMEApplicationJSON].Schema.Ref != "#/components/schemas/HealthStatusResponse" {
+			t.Errorf("got readiness operation %+v", ready)
+		}
+	})
+	t.Run("should not serve admin endpoints on public listener unless allowed", func(t *testing.T) {
+		auth := api.AdminBasicAuth("admin", "secret")
+		_, err := api.NewServer("svc", "1.0.0", logger.NewNopLogger(), api.WithAdminRoutes(api.AdminConfig{Auth: auth}))
+		if err == nil {
+			t.Fatal("NewServer should fail without admin address")
+		}
+		s := newServer(t, api.WithOpenAPI(api.OpenAPIConfig{}), api.WithAdminRoutes(api.AdminConfig{Auth: auth, AllowPublic: true}))
+		if rec := get(s.Public, api.AdminPath+api.VersionPath); rec.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d, expected admin endpoint to be served", rec.Code)
+		}
+		var doc api.OpenAPIDocument
+		if err = json.Unmarshal(get(s.Public, api.OpenAPIPath).Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		for path := range doc.Paths {
+			if strings.HasPrefix(path, api.AdminPath) {
+				t.Errorf("admin endpoint %s should not be advertised", path)
+			}
+		}
+		if doc.Paths[api.LivenessPath] == nil {
+			t.Errorf("got paths %v, expected health endpoints", doc.Paths)
+		}
+	})
+	t.Run("should report draining by readiness probe within drain period", func(t *testing.T) {
+		const drainPeriod = time.Second
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGUSR1)
+		defer signal.Stop(sigs)
+		s, err := api.NewServer("svc", "1.0.0", logger.NewNopLogger(),
+			api.WithPublicAddress("127.0.0.1:0"),
+			api.WithLifecycleConfig(api.LifecycleConfig{DrainPeriod: drainPeriod, Signals: []os.Signal{syscall.SIGUSR1}}))
+		if err != nil {
+			t.Fatalf("NewServer returned error: %v", err)
+		}
+		done := make(chan error)
+		go func() { done <- s.Run(context.Background()) }()
+		waitStatus := func(code int, timeout time.Duration) bool {
+			deadline := time.Now().Add(timeout)
+			for time.Now().Before(deadline) {
+				if get(s.Admin, api.ReadinessPath).Code == code {
+					return true
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			return false
+		}
+		if !waitStatus(http.StatusOK, time.Second) {
+			t.Fatal("service should be ready after warm-up")
+		}
+		if err = syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatal(err)
+		}
+		if !waitStatus(http.StatusServiceUnavailable, drainPeriod/2) {
+			t.Error("readiness probe should fail during drain period")
+		}
+		if err = <-done; err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	})
+	t.Run("should warm up and disconnect repositories on shutdown", func(t *testing.T) {
+		repo := &fakeRepository{}
+		warmed := make(chan struct{})
+		s := newServer(t,
+			api.WithRepository("db", repo),
+			api.WithWarmupHook("indexes", func(context.Context) error {
+				close(warmed)
+				return nil
+			}))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- s.Run(ctx) }()
+		select {
+		case <-warmed:
+		case <-time.After(time.Second):
+			t.Fatal("warm-up hook was not called")
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+		if !repo.disconnected {
+			t.Error("repository should be disconnected")
+		}
+	})
+}
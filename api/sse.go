@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+const (
+	// HeaderLastEventID is request header with id of last event received by reconnecting client
+	HeaderLastEventID = "Last-Event-ID"
+	// QueryLastEventID is query parameter alternative to Last-Event-ID header (ex. for first connection of page)
+	QueryLastEventID = "lastEventId"
+	// MIMETextEventStream is content type of server-sent events stream
+	MIMETextEventStream = "text/event-stream"
+
+	// DefaultSSEHeartbeatInterval is default interval of heartbeat comments keeping idle stream alive
+	DefaultSSEHeartbeatInterval = 15 * time.Second
+)
+
+// SSEConfig is configuration of server-sent events handler
+type SSEConfig struct {
+	// HeartbeatInterval is interval of heartbeat comments sent to idle stream
+	HeartbeatInterval time.Duration
+	// BufferSize is number of events buffered for connection,
+	// connection is closed if client does not read events in time
+	BufferSize int
+	// Retry is reconnection time sent to client (not sent if 0)
+	Retry time.Duration
+	// Draining is channel closed when service starts graceful draining (ex. Lifecycle.DrainStarted),
+	// open streams are completed and new streams are rejected to let clients reconnect to other instances
+	Draining <-chan struct{}
+}
+
+// DefaultSSEConfig returns default SSEConfig
+func DefaultSSEConfig() SSEConfig {
+	return SSEConfig{
+		HeartbeatInterval: DefaultSSEHeartbeatInterval,
+		BufferSize:        DefaultEventBufferSize,
+	}
+}
+
+// SSEHandler creates handler streaming events of request namespace from source as server-sent events,
+// events published after Last-Event-ID are delivered first if they are still kept by source.
+// Event data is serialized to JSON. Stream requests are skipped by Timeout and ConcurrencyLimiter middlewares
+// by default (see EventStreamSkipper), custom skippers of these middlewares should include it.
+func SSEHandler[T any](source *EventSource[T], cfg SSEConfig) echo.HandlerFunc {
+	def := DefaultSSEConfig()
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = def.HeartbeatInterval
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = def.BufferSize
+	}
+	return func(c echo.Context) error {
+		if isClosed(cfg.Draining) {
+			return apperrors.NewAppError(apperrors.ErrorSvcOverloaded, "service is draining")
+		}
+		lastID, err := lastEventID(c)
+		if err != nil {
+			return err
+		}
+		sub := source.Subscribe(GetNamespace(c), lastID, cfg.BufferSize)
+		defer sub.Close()
+
+		res := c.Response()
+		h := res.Header()
+		h.Set(echo.HeaderContentType, MIMETextEventStream)
+		h.Set(echo.HeaderCacheControl, "no-cache")
+		h.Set(echo.HeaderConnection, "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+		if cfg.Retry > 0 {
+			if _, err = fmt.Fprintf(res, "retry: %d\n\n", cfg.Retry.Milliseconds()); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+
+		heartbeat := time.NewTicker(cfg.HeartbeatInterval)
+		defer heartbeat.Stop()
+		done := c.Request().Context().Done()
+		for {
+			select {
+			case <-done:
+				return nil
+			case <-cfg.Draining:
+				return nil
+			case <-heartbeat.C:
+				if _, err = fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+			case ev, ok := <-sub.C:
+				if !ok {
+					// slow consumer or closed source, client resumes stream after reconnect
+					return nil
+				}
+				if err = writeEvent(res, ev); err != nil {
+					return nil
+				}
+			}
+			res.Flush()
+		}
+	}
+}
+
+// EventStreamSkipper is middleware.Skipper of requests accepting server-sent events stream
+// (EventSource sends Accept: text/event-stream)
+func EventStreamSkipper(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMETextEventStream)
+}
+
+// writeEvent writes event in text/event-stream format
+func writeEvent[T any](res *echo.Response, ev Event[T]) error {
+	payload, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	if ev.Type != "" {
+		_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, payload)
+	} else {
+		_, err = fmt.Fprintf(res, "id: %d\ndata: %s\n\n", ev.ID, payload)
+	}
+	return err
+}
+
+// lastEventID returns id of last event received by client (0 if it is not provided)
+func lastEventID(c echo.Context) (uint64, error) {
+	val := c.Request().Header.Get(HeaderLastEventID)
+	field := HeaderLastEventID
+	if val == "" {
+		val, field = c.QueryParam(QueryLastEventID), QueryLastEventID
+	}
+	if val == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, apperrors.NewValidationError("invalid last event id", []apperrors.FieldError{{
+			Field:   field,
+			Message: "should be non-negative integer",
+		}})
+	}
+	return id, nil
+}
+
+// isClosed reports if ch is closed (nil channel is never closed)
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
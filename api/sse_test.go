@@ -0,0 +1,180 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+type campaignStatus struct {
+	Campaign string `json:"campaign"`
+	Progress int    `json:"progress"`
+}
+
+func TestEventSource(t *testing.T) {
+	t.Run("should deliver events of subscribed namespace", func(t *testing.T) {
+		src := api.NewEventSource[int](10)
+		sub := src.Subscribe("ns1", 0, 10)
+		defer sub.Close()
+		src.Publish("ns2", "", 1)
+		src.Publish("ns1", "", 2)
+		if ev := <-sub.C; ev.Data != 2 || ev.Namespace != "ns1" {
+			t.Errorf("got event %+v", ev)
+		}
+	})
+	t.Run("should replay events after last id", func(t *testing.T) {
+		src := api.NewEventSource[int](2)
+		for i := 1; i <= 3; i++ {
+			src.Publish("ns", "", i)
+		}
+		sub := src.Subscribe("ns", 1, 10)
+		defer sub.Close()
+		for _, expected := range []uint64{2, 3} {
+			if ev := <-sub.C; ev.ID != expected {
+				t.Errorf("got event %d, expected %d", ev.ID, expected)
+			}
+		}
+	})
+	t.Run("should disconnect slow consumer", func(t *testing.T) {
+		src := api.NewEventSource[int](10)
+		sub := src.Subscribe("ns", 0, 1)
+		src.Publish("ns", "", 1)
+		src.Publish("ns", "", 2)
+		<-sub.C
+		if _, ok := <-sub.C; ok {
+			t.Error("expected subscription to be closed")
+		}
+		if !errors.Is(sub.Err(), api.ErrSlowConsumer) {
+			t.Errorf("got error %v, expected %v", sub.Err(), api.ErrSlowConsumer)
+		}
+		if src.Subscribers() != 0 {
+			t.Errorf("got %d subscribers, expected 0", src.Subscribers())
+		}
+	})
+}
+
+func TestSSEHandler(t *testing.T) {
+	src := api.NewEventSource[campaignStatus](0)
+	draining := make(chan struct{})
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	e.GET("/events", api.SSEHandler(src, api.SSEConfig{HeartbeatInterval: 20 * time.Millisecond, Draining: draining}))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	connect := func(t *testing.T, ns data.Namespace, lastID string) (*bufio.Reader, func()) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+		req.Header.Set("x-ats-namespace", string(ns))
+		if lastID != "" {
+			req.Header.Set(api.HeaderLastEventID, lastID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if ct := res.Header.Get(echo.HeaderContentType); ct != api.MIMETextEventStream {
+			t.Fatalf("got content type %q", ct)
+		}
+		return bufio.NewReader(res.Body), func() {
+			cancel()
+			_ = res.Body.Close()
+		}
+	}
+	// readMessage returns next message of stream skipping heartbeats
+	readMessage := func(t *testing.T, r *bufio.Reader, skipHeartbeat bool) string {
+		t.Helper()
+		var msg strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if line == "\n" {
+				if skipHeartbeat && msg.String() == ": heartbeat\n" {
+					msg.Reset()
+					continue
+				}
+				return msg.String()
+			}
+			msg.WriteString(line)
+		}
+	}
+	waitSubscribers := func(n int) {
+		for i := 0; i < 100 && src.Subscribers() != n; i++ {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("should stream events of namespace and resume after last event id", func(t *testing.T) {
+		r, closeStream := connect(t, "ns1", "")
+		waitSubscribers(1)
+		src.Publish("ns2", "campaign.progress", campaignStatus{Campaign: "other", Progress: 1})
+		src.Publish("ns1", "campaign.progress", campaignStatus{Campaign: "c1", Progress: 50})
+		src.Publish("ns1", "campaign.progress", campaignStatus{Campaign: "c1", Progress: 100})
+		expected := "id: 2\nevent: campaign.progress\ndata: {\"campaign\":\"c1\",\"progress\":50}\n"
+		if msg := readMessage(t, r, true); msg != expected {
+			t.Errorf("got message %q, expected %q", msg, expected)
+		}
+		closeStream()
+		waitSubscribers(0)
+
+		r, closeStream = connect(t, "ns1", "2")
+		defer closeStream()
+		if msg := readMessage(t, r, true); !strings.HasPrefix(msg, "id: 3\n") {
+			t.Errorf("got resumed message %q", msg)
+		}
+	})
+	t.Run("should send heartbeat", func(t *testing.T) {
+		r, closeStream := connect(t, "ns1", "")
+		defer closeStream()
+		if msg := readMessage(t, r, false); msg != ": heartbeat\n" {
+			t.Errorf("got message %q", msg)
+		}
+	})
+	t.Run("should reject invalid last event id", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/events?" + api.QueryLastEventID + "=abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("got status %d, expected %d", res.StatusCode, http.StatusBadRequest)
+		}
+	})
+	t.Run("should complete streams on draining", func(t *testing.T) {
+		r, closeStream := connect(t, "ns1", "")
+		defer closeStream()
+		waitSubscribers(1)
+		close(draining)
+		done := make(chan error, 1)
+		go func() {
+			_, err := r.ReadString('\x00')
+			done <- err
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stream was not completed")
+		}
+		res, err := http.Get(srv.URL + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("got status %d, expected %d", res.StatusCode, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// HeaderContentSHA256 is request header with expected hex encoded SHA-256 digest of uploaded content
+	HeaderContentSHA256 = "X-Content-Sha256"
+	// HeaderContentSHA512 is request header with expected hex encoded SHA-512 digest of uploaded content
+	HeaderContentSHA512 = "X-Content-Sha512"
+	// QuerySHA256 is query parameter with expected hex encoded SHA-256 digest of uploaded content
+	QuerySHA256 = "sha256"
+	// QuerySHA512 is query parameter with expected hex encoded SHA-512 digest of uploaded content
+	QuerySHA512 = "sha512"
+
+	// uploadBufferSize is size of buffer used to copy request body to sink
+	uploadBufferSize = 64 << 10
+)
+
+// UploadSink is destination of uploaded content,
+// content is written to sink and then committed if it passed verification or aborted otherwise
+type UploadSink interface {
+	io.Writer
+	// Commit makes uploaded content available
+	Commit() error
+	// Abort removes partially uploaded content
+	Abort() error
+}
+
+// UploadConfig is configuration of Upload
+type UploadConfig struct {
+	// MaxSize is maximum size of uploaded content (not limited if 0)
+	MaxSize int64
+	// RequireContentLength rejects requests without Content-Length header (ex. chunked requests)
+	RequireContentLength bool
+	// RequireDigest rejects requests without expected SHA-256 digest
+	RequireDigest bool
+	// SHA512 enables calculation of SHA-512 digest (always enabled if expected SHA-512 digest is provided)
+	SHA512 bool
+}
+
+// UploadResult is result of successful upload
+type UploadResult struct {
+	// Size is number of uploaded bytes
+	Size int64
+	// SHA256 is hex encoded SHA-256 digest of uploaded content
+	SHA256 string
+	// SHA512 is hex encoded SHA-512 digest of uploaded content (if enabled)
+	SHA512 string
+}
+
+// Upload streams request body to sink calculating its digests on the fly.
+// Size of content should match Content-Length header and its digests should match ones provided
+// by X-Content-Sha256/X-Content-Sha512 headers or sha256/sha512 query parameters.
+// Sink is aborted if upload fails, so partial content is not kept.
+func Upload(ctx echo.Context, sink UploadSink, cfg UploadConfig) (res UploadResult, err error) {
+	defer func() {
+		if err != nil {
+			if abortErr := sink.Abort(); abortErr != nil {
+				ctx.Logger().Errorf("Failed to abort upload. Error: %v", abortErr)
+			}
+		}
+	}()
+
+	req := ctx.Request()
+	declared := GetContentSize(ctx)
+	if cfg.RequireContentLength && req.Header.Get(echo.HeaderContentLength) == "" {
+		return res, apperrors.NewAppError(apperrors.ErrorDataValidation, "Content-Length header is required")
+	}
+	if cfg.MaxSize > 0 && declared > cfg.MaxSize {
+		return res, errPayloadTooLarge(cfg.MaxSize)
+	}
+	expected256, err := expectedDigest(ctx, HeaderContentSHA256, QuerySHA256, sha256.Size)
+	if err != nil {
+		return res, err
+	}
+	if cfg.RequireDigest && expected256 == "" {
+		return res, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("expected SHA-256 digest is required (%s header or %s query parameter)", HeaderContentSHA256, QuerySHA256))
+	}
+	expected512, err := expectedDigest(ctx, HeaderContentSHA512, QuerySHA512, sha512.Size)
+	if err != nil {
+		return res, err
+	}
+
+	h256 := sha256.New()
+	hashes := []io.Writer{sink, h256}
+	var h512 hash.Hash
+	if cfg.SHA512 || expected512 != "" {
+		h512 = sha512.New()
+		hashes = append(hashes, h512)
+	}
+	// read one byte over the limit to detect oversized body
+	limit := declared
+	if limit <= 0 || (cfg.MaxSize > 0 && cfg.MaxSize < limit) {
+		limit = cfg.MaxSize
+	}
+	body := &uploadReader{r: req.Body}
+	var src io.Reader = body
+	if limit > 0 {
+		src = io.LimitReader(body, limit+1)
+	}
+	res.Size, err = io.CopyBuffer(io.MultiWriter(hashes...), src, make([]byte, uploadBufferSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(body.err, &maxBytesErr) {
+			return res, errPayloadTooLarge(maxBytesErr.Limit)
+		}
+		if body.err != nil {
+			return res, apperrors.CreateError(apperrors.ErrorDataValidation, "failed to read request body", body.err)
+		}
+		var appErr apperrors.AppError
+		if errors.As(err, &appErr) {
+			return res, err
+		}
+		return res, apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to write uploaded content", err)
+	}
+	if cfg.MaxSize > 0 && res.Size > cfg.MaxSize {
+		return res, errPayloadTooLarge(cfg.MaxSize)
+	}
+	if declared > 0 && res.Size != declared {
+		return res, apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("size of request body does not match Content-Length %d", declared))
+	}
+
+	res.SHA256 = hex.EncodeToString(h256.Sum(nil))
+	if h512 != nil {
+		res.SHA512 = hex.EncodeToString(h512.Sum(nil))
+	}
+	var details []apperrors.FieldError
+	if expected256 != "" && expected256 != res.SHA256 {
+		details = append(details, digestMismatch(HeaderContentSHA256, expected256, res.SHA256))
+	}
+	if expected512 != "" && expected512 != res.SHA512 {
+		details = append(details, digestMismatch(HeaderContentSHA512, expected512, res.SHA512))
+	}
+	if len(details) > 0 {
+		return res, apperrors.NewValidationError("digest of uploaded content does not match expected one", details)
+	}
+
+	if err = sink.Commit(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// expectedDigest returns expected hex encoded digest from header or query parameter
+func expectedDigest(ctx echo.Context, header, query string, size int) (string, error) {
+	v := ctx.Request().Header.Get(header)
+	if v == "" {
+		v = ctx.QueryParam(query)
+	}
+	if v == "" {
+		return "", nil
+	}
+	v = strings.ToLower(v)
+	if !data.ValidHex(2*size, v) {
+		return "", apperrors.NewValidationError("invalid expected digest", []apperrors.FieldError{{
+			Field:   header,
+			Message: fmt.Sprintf("should be %d hex characters", 2*size),
+		}})
+	}
+	return v, nil
+}
+
+func digestMismatch(field, expected, actual string) apperrors.FieldError {
+	return apperrors.FieldError{
+		Field:   field,
+		Message: fmt.Sprintf("expected %s, got %s", expected, actual),
+	}
+}
+
+// uploadReader keeps read error of request body to distinguish it from write error of sink
+type uploadReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// FileUploadSink is UploadSink writing content to temporary file renamed to destination path on commit
+type FileUploadSink struct {
+	path string
+	file *os.File
+}
+
+// NewFileUploadSink creates FileUploadSink of file path (temporary file is created in the same directory)
+func NewFileUploadSink(path string) (*FileUploadSink, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, apperrors.CreateError(apperrors.ErrorFsIOCreate, "failed to create upload file", err)
+	}
+	return &FileUploadSink{path: path, file: f}, nil
+}
+
+// Write writes content to temporary file
+func (s *FileUploadSink) Write(p []byte) (int, error) {
+	return s.file.Write(p)
+}
+
+// Commit syncs temporary file and renames it to destination path
+func (s *FileUploadSink) Commit() error {
+	if err := s.file.Sync(); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to sync upload file", err)
+	}
+	if err := s.file.Close(); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to close upload file", err)
+	}
+	if err := os.Rename(s.file.Name(), s.path); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to move upload file", err)
+	}
+	return nil
+}
+
+// Abort removes temporary file
+func (s *FileUploadSink) Abort() error {
+	_ = s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to remove upload file", err)
+	}
+	return nil
+}
@@ -0,0 +1,567 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// DefaultUploadSessionTTL is default lifetime of multipart upload session
+	DefaultUploadSessionTTL = 24 * time.Hour
+	// DefaultMaxUploadSessions is default limit of concurrent upload sessions of namespace
+	DefaultMaxUploadSessions = 10
+	// DefaultMaxUploadParts is default limit of parts of upload session
+	DefaultMaxUploadParts = 10000
+	// DefaultMaxUploadPartSize is default limit of upload part size
+	DefaultMaxUploadPartSize int64 = 1 << 30
+	// DefaultUploadCleanupInterval is default interval of expired upload sessions removal
+	DefaultUploadCleanupInterval = 10 * time.Minute
+	// DefaultUploadCompleteTimeout is default lease time of completing upload session
+	DefaultUploadCompleteTimeout = 30 * time.Minute
+
+	// uploadCleanupBatch is maximum number of expired sessions removed at once
+	uploadCleanupBatch = 100
+)
+
+// UploadSessionConfig is configuration of UploadSessions
+type UploadSessionConfig struct {
+	// TTL is lifetime of upload session
+	TTL time.Duration
+	// MaxSessions is limit of concurrent (not completed) upload sessions of namespace
+	MaxSessions int
+	// MaxParts is limit of parts of upload session
+	MaxParts int
+	// MaxPartSize is limit of upload part size
+	MaxPartSize int64
+	// CleanupInterval is interval of expired sessions removal
+	CleanupInterval time.Duration
+	// CompleteTimeout is deadline of session completion and lease time of completing session,
+	// session not completed in time can be completed again or aborted
+	CompleteTimeout time.Duration
+}
+
+// DefaultUploadSessionConfig returns default configuration of UploadSessions
+func DefaultUploadSessionConfig() UploadSessionConfig {
+	return UploadSessionConfig{
+		TTL:             DefaultUploadSessionTTL,
+		MaxSessions:     DefaultMaxUploadSessions,
+		MaxParts:        DefaultMaxUploadParts,
+		MaxPartSize:     DefaultMaxUploadPartSize,
+		CleanupInterval: DefaultUploadCleanupInterval,
+		CompleteTimeout: DefaultUploadCompleteTimeout,
+	}
+}
+
+// UploadSessionStore is storage of upload sessions state
+type UploadSessionStore interface {
+	// Create stores new session
+	Create(ctx context.Context, s *UploadSession) error
+	// Active returns number of not completed and not expired sessions of namespace
+	Active(ctx context.Context, ns data.Namespace, now time.Time) (int64, error)
+	// Get returns session (AppError with ErrorDbNoDocumentFound code if session does not exist)
+	Get(ctx context.Context, ns data.Namespace, id string) (*UploadSession, error)
+	// PutPart stores part of open session replacing part with the same number
+	// (AppError with ErrorSvcInvalidState code if session is not open)
+	PutPart(ctx context.Context, ns data.Namespace, id string, part UploadPart) error
+	// SetStatus changes status of session
+	// (AppError with ErrorSvcInvalidState code if current status is not expected one)
+	SetStatus(ctx context.Context, ns data.Namespace, id string, from, to UploadSessionStatus) error
+	// LockCompletion changes status of open session or completing session with lease expired before now
+	// to completing with lease until lockedUntil (AppError with ErrorSvcInvalidState code otherwise)
+	LockCompletion(ctx context.Context, ns data.Namespace, id string, now, lockedUntil time.Time) error
+	// Delete removes session
+	Delete(ctx context.Context, ns data.Namespace, id string) error
+	// Expired returns up to limit sessions expired before now
+	Expired(ctx context.Context, now time.Time, limit int) ([]UploadSession, error)
+}
+
+// UploadPartStorage is storage of upload parts content
+type UploadPartStorage interface {
+	// PartSink returns sink of part content and key of content in storage
+	PartSink(ctx context.Context, sessionID string, number int) (UploadSink, string, error)
+	// OpenPart returns reader of part content
+	OpenPart(ctx context.Context, sessionID string, part UploadPart) (io.ReadCloser, error)
+	// DeleteParts removes content of all parts of session
+	DeleteParts(ctx context.Context, sessionID string) error
+}
+
+// UploadSessions manages multipart upload sessions: parts are uploaded in any order,
+// on completion they are concatenated and SHA-256 digest of content is verified
+type UploadSessions struct {
+	store UploadSessionStore
+	parts UploadPartStorage
+	cfg   UploadSessionConfig
+}
+
+// NewUploadSessions creates new UploadSessions
+func NewUploadSessions(store UploadSessionStore, parts UploadPartStorage, cfg UploadSessionConfig) *UploadSessions {
+	def := DefaultUploadSessionConfig()
+	if cfg.TTL <= 0 {
+		cfg.TTL = def.TTL
+	}
+	if cfg.MaxSessions <= 0 {
+		cfg.MaxSessions = def.MaxSessions
+	}
+	if cfg.MaxParts <= 0 {
+		cfg.MaxParts = def.MaxParts
+	}
+	if cfg.MaxPartSize <= 0 {
+		cfg.MaxPartSize = def.MaxPartSize
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = def.CleanupInterval
+	}
+	if cfg.CompleteTimeout <= 0 {
+		cfg.CompleteTimeout = def.CompleteTimeout
+	}
+	return &UploadSessions{store: store, parts: parts, cfg: cfg}
+}
+
+// Create creates upload session of namespace
+func (m *UploadSessions) Create(ctx context.Context, ns data.Namespace, req CreateUploadSessionRequest) (*UploadSession, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	if req.Size < 0 {
+		return nil, apperrors.NewValidationError("invalid upload session", []apperrors.FieldError{{
+			Field:   "size",
+			Message: "should not be negative",
+		}})
+	}
+	now := time.Now().UTC()
+	s := &UploadSession{
+		ID:        data.NewCorrelationID().String(),
+		Namespace: ns,
+		Status:    UploadSessionOpen,
+		Size:      req.Size,
+		SHA256:    req.SHA256,
+		Parts:     []UploadPart{},
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.cfg.TTL),
+	}
+	// limit is not strict, concurrent requests could exceed it
+	active, err := m.store.Active(ctx, ns, now)
+	if err != nil {
+		return nil, err
+	}
+	if active >= int64(m.cfg.MaxSessions) {
+		return nil, apperrors.NewAppError(apperrors.ErrorSvcQuotaExceeded,
+			fmt.Sprintf("namespace has reached limit of %d concurrent upload sessions", m.cfg.MaxSessions))
+	}
+	if err = m.store.Create(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns not expired upload session
+func (m *UploadSessions) Get(ctx context.Context, ns data.Namespace, id string) (*UploadSession, error) {
+	s, err := m.store.Get(ctx, ns, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.ExpiresAt.Before(time.Now()) {
+		return nil, apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "upload session expired")
+	}
+	return s, nil
+}
+
+// UploadPart streams request body to part of upload session of request namespace
+// (expected digest of part is checked, see Upload)
+func (m *UploadSessions) UploadPart(ctx echo.Context, id string, number int) (*UploadPart, error) {
+	if number < 1 || number > m.cfg.MaxParts {
+		return nil, apperrors.NewValidationError("invalid upload part", []apperrors.FieldError{{
+			Field:   "number",
+			Message: fmt.Sprintf("should be in range [1, %d]", m.cfg.MaxParts),
+		}})
+	}
+	reqCtx := GetRequestContext(ctx)
+	ns := GetNamespace(ctx)
+	s, err := m.Get(reqCtx, ns, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != UploadSessionOpen {
+		return nil, errSessionNotOpen(s)
+	}
+	sink, key, err := m.parts.PartSink(reqCtx, s.ID, number)
+	if err != nil {
+		return nil, err
+	}
+	res, err := Upload(ctx, sink, UploadConfig{MaxSize: m.cfg.MaxPartSize})
+	if err != nil {
+		return nil, err
+	}
+	part := UploadPart{
+		Number:     number,
+		Size:       res.Size,
+		SHA256:     res.SHA256,
+		Key:        key,
+		UploadedAt: time.Now().UTC(),
+	}
+	if err = m.store.PutPart(reqCtx, ns, s.ID, part); err != nil {
+		return nil, err
+	}
+	return &part, nil
+}
+
+// Complete concatenates parts of upload session to sink and verifies size and SHA-256 digest of content,
+// sink is committed if content is valid, otherwise it is aborted and session stays open,
+// session is locked for CompleteTimeout and can be completed again if lock expires (ex. instance crashed).
+// Completion is not limited by request deadline (see RegisterUploadSessionRoutes) but has own CompleteTimeout deadline
+func (m *UploadSessions) Complete(ctx context.Context, ns data.Namespace, id string, sink UploadSink) (_ *UploadSession, err error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.CompleteTimeout)
+	defer cancel()
+	s, err := m.Get(ctx, ns, id)
+	if err != nil {
+		_ = sink.Abort()
+		return nil, err
+	}
+	now := time.Now()
+	if err = m.store.LockCompletion(ctx, ns, s.ID, now, now.Add(m.cfg.CompleteTimeout)); err != nil {
+		_ = sink.Abort()
+		return nil, err
+	}
+	// status changes are not canceled with request (ex. by client disconnect or request timeout),
+	// otherwise session stays completing until expiration and can be neither retried nor aborted
+	storeCtx := context.WithoutCancel(ctx)
+	defer func() {
+		if err != nil {
+			_ = sink.Abort()
+			_ = m.store.SetStatus(storeCtx, ns, s.ID, UploadSessionCompleting, UploadSessionOpen)
+		}
+	}()
+	// parts can be changed until session status is changed
+	if s, err = m.store.Get(ctx, ns, s.ID); err != nil {
+		return nil, err
+	}
+	if err = checkUploadParts(s); err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	w := io.MultiWriter(sink, h)
+	for _, part := range s.Parts {
+		if err = ctx.Err(); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = apperrors.NewAppError(apperrors.ErrorSvcTimeout,
+					fmt.Sprintf("upload session was not completed in %s", m.cfg.CompleteTimeout))
+			}
+			return nil, err
+		}
+		if err = m.copyPart(ctx, w, s.ID, part); err != nil {
+			return nil, err
+		}
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != s.SHA256 {
+		return nil, apperrors.NewValidationError("digest of uploaded content does not match expected one",
+			[]apperrors.FieldError{digestMismatch("sha256", s.SHA256, sum)})
+	}
+	if err = sink.Commit(); err != nil {
+		return nil, err
+	}
+	if err = m.store.SetStatus(storeCtx, ns, s.ID, UploadSessionCompleting, UploadSessionCompleted); err != nil {
+		return nil, err
+	}
+	s.Status = UploadSessionCompleted
+	// content of parts is removed with expired session if it fails now
+	_ = m.parts.DeleteParts(storeCtx, s.ID)
+	return s, nil
+}
+
+func (m *UploadSessions) copyPart(ctx context.Context, w io.Writer, id string, part UploadPart) error {
+	rc, err := m.parts.OpenPart(ctx, id, part)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	n, err := io.Copy(w, rc)
+	if err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation,
+			fmt.Sprintf("failed to read content of part %d", part.Number), err)
+	}
+	if n != part.Size {
+		return apperrors.NewAppError(apperrors.ErrorFsIOOperation,
+			fmt.Sprintf("size of stored part %d is %d bytes, expected %d", part.Number, n, part.Size))
+	}
+	return nil
+}
+
+// Abort removes not completed upload session and content of its parts,
+// completing session is removed only if its lease is expired
+func (m *UploadSessions) Abort(ctx context.Context, ns data.Namespace, id string) error {
+	s, err := m.store.Get(ctx, ns, id)
+	if err != nil {
+		return err
+	}
+	if s.Status == UploadSessionCompleting && !s.LeaseExpired(time.Now()) {
+		return errSessionNotOpen(s)
+	}
+	if err = m.parts.DeleteParts(ctx, s.ID); err != nil {
+		return err
+	}
+	return m.store.Delete(ctx, ns, s.ID)
+}
+
+// Cleanup removes expired upload sessions and content of their parts, it returns number of removed sessions
+func (m *UploadSessions) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	for {
+		expired, err := m.store.Expired(ctx, now, uploadCleanupBatch)
+		if err != nil {
+			return removed, err
+		}
+		for _, s := range expired {
+			if err = m.parts.DeleteParts(ctx, s.ID); err != nil {
+				return removed, err
+			}
+			if err = m.store.Delete(ctx, s.Namespace, s.ID); err != nil &&
+				apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbNoDocumentFound {
+				return removed, err
+			}
+			removed++
+		}
+		if len(expired) < uploadCleanupBatch {
+			return removed, nil
+		}
+	}
+}
+
+// Run removes expired upload sessions on interval until ctx is canceled
+func (m *UploadSessions) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		_, _ = m.Cleanup(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkUploadParts verifies that parts are numbered sequentially from 1 and their size matches expected one
+func checkUploadParts(s *UploadSession) error {
+	if len(s.Parts) == 0 {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation, "upload session has no parts")
+	}
+	var size int64
+	for i, part := range s.Parts {
+		if part.Number != i+1 {
+			return apperrors.NewAppError(apperrors.ErrorDataValidation,
+				fmt.Sprintf("part %d of upload session is missing", i+1))
+		}
+		size += part.Size
+	}
+	if s.Size > 0 && size != s.Size {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			fmt.Sprintf("size of uploaded parts is %d bytes, expected %d", size, s.Size))
+	}
+	return nil
+}
+
+func errSessionNotOpen(s *UploadSession) error {
+	return apperrors.NewAppError(apperrors.ErrorSvcInvalidState,
+		fmt.Sprintf("upload session is %s", s.Status))
+}
+
+// FilePartStorage is UploadPartStorage keeping parts content in files (directory per session)
+type FilePartStorage struct {
+	dir string
+}
+
+// NewFilePartStorage creates FilePartStorage in directory
+func NewFilePartStorage(dir string) *FilePartStorage {
+	return &FilePartStorage{dir: dir}
+}
+
+// PartSink returns sink of part content, each upload of part is stored in separate file
+// so concurrent uploads of the same part do not overwrite each other
+func (s *FilePartStorage) PartSink(_ context.Context, sessionID string, number int) (UploadSink, string, error) {
+	dir := filepath.Join(s.dir, filepath.Base(sessionID))
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, "", apperrors.CreateError(apperrors.ErrorFsIOCreate, "failed to create upload directory", err)
+	}
+	key := fmt.Sprintf("%d-%s.part", number, data.NewCorrelationID())
+	sink, err := NewFileUploadSink(filepath.Join(dir, key))
+	if err != nil {
+		return nil, "", err
+	}
+	return sink, key, nil
+}
+
+// OpenPart returns reader of part content
+func (s *FilePartStorage) OpenPart(_ context.Context, sessionID string, part UploadPart) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.Base(sessionID), filepath.Base(part.Key)))
+	if err != nil {
+		return nil, apperrors.CreateError(apperrors.ErrorFsIOOpen,
+			fmt.Sprintf("failed to open content of part %d", part.Number), err)
+	}
+	return f, nil
+}
+
+// DeleteParts removes content of all parts of session
+func (s *FilePartStorage) DeleteParts(_ context.Context, sessionID string) error {
+	if err := os.RemoveAll(filepath.Join(s.dir, filepath.Base(sessionID))); err != nil {
+		return apperrors.CreateError(apperrors.ErrorFsIOOperation, "failed to remove upload parts", err)
+	}
+	return nil
+}
+
+// MemoryUploadSessionStore is in-memory UploadSessionStore (for tests and single instance services)
+type MemoryUploadSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]UploadSession
+}
+
+// NewMemoryUploadSessionStore creates new MemoryUploadSessionStore
+func NewMemoryUploadSessionStore() *MemoryUploadSessionStore {
+	return &MemoryUploadSessionStore{sessions: make(map[string]UploadSession)}
+}
+
+// Create stores new session
+func (s *MemoryUploadSessionStore) Create(_ context.Context, session *UploadSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.sessions[session.ID]; found {
+		return apperrors.NewAppError(apperrors.ErrorDbAlreadyExist, "upload session already exists")
+	}
+	s.sessions[session.ID] = copyUploadSession(*session)
+	return nil
+}
+
+// Active returns number of not completed and not expired sessions of namespace
+func (s *MemoryUploadSessionStore) Active(_ context.Context, ns data.Namespace, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var active int64
+	for _, session := range s.sessions {
+		if session.Namespace == ns && session.Status != UploadSessionCompleted && session.ExpiresAt.After(now) {
+			active++
+		}
+	}
+	return active, nil
+}
+
+// Get returns session
+func (s *MemoryUploadSessionStore) Get(_ context.Context, ns data.Namespace, id string) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.get(ns, id)
+	if err != nil {
+		return nil, err
+	}
+	res := copyUploadSession(session)
+	return &res, nil
+}
+
+// PutPart stores part of open session replacing part with the same number
+func (s *MemoryUploadSessionStore) PutPart(_ context.Context, ns data.Namespace, id string, part UploadPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.get(ns, id)
+	if err != nil {
+		return err
+	}
+	if session.Status != UploadSessionOpen {
+		return errSessionNotOpen(&session)
+	}
+	parts := make([]UploadPart, 0, len(session.Parts)+1)
+	for _, p := range session.Parts {
+		if p.Number != part.Number {
+			parts = append(parts, p)
+		}
+	}
+	session.Parts = append(parts, part)
+	sort.Slice(session.Parts, func(i, j int) bool { return session.Parts[i].Number < session.Parts[j].Number })
+	s.sessions[id] = session
+	return nil
+}
+
+// SetStatus changes status of session
+func (s *MemoryUploadSessionStore) SetStatus(_ context.Context, ns data.Namespace, id string, from, to UploadSessionStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.get(ns, id)
+	if err != nil {
+		return err
+	}
+	if session.Status != from {
+		return errSessionNotOpen(&session)
+	}
+	session.Status = to
+	s.sessions[id] = session
+	return nil
+}
+
+// LockCompletion changes status of open session or completing session with expired lease to completing
+func (s *MemoryUploadSessionStore) LockCompletion(_ context.Context, ns data.Namespace, id string, now, lockedUntil time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.get(ns, id)
+	if err != nil {
+		return err
+	}
+	if session.Status != UploadSessionOpen && !session.LeaseExpired(now) {
+		return errSessionNotOpen(&session)
+	}
+	session.Status = UploadSessionCompleting
+	session.LockedUntil = lockedUntil
+	s.sessions[id] = session
+	return nil
+}
+
+// Delete removes session
+func (s *MemoryUploadSessionStore) Delete(_ context.Context, ns data.Namespace, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(ns, id); err != nil {
+		return err
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// Expired returns up to limit sessions expired before now
+func (s *MemoryUploadSessionStore) Expired(_ context.Context, now time.Time, limit int) ([]UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []UploadSession
+	for _, session := range s.sessions {
+		if len(res) == limit {
+			break
+		}
+		if !session.ExpiresAt.After(now) {
+			res = append(res, copyUploadSession(session))
+		}
+	}
+	return res, nil
+}
+
+func (s *MemoryUploadSessionStore) get(ns data.Namespace, id string) (UploadSession, error) {
+	session, found := s.sessions[id]
+	if !found || session.Namespace != ns {
+		return UploadSession{}, apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "upload session not found")
+	}
+	return session, nil
+}
+
+func copyUploadSession(s UploadSession) UploadSession {
+	s.Parts = append([]UploadPart{}, s.Parts...)
+	return s
+}
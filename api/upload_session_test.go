@@ -0,0 +1,271 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+// contextStore is upload session store failing status changes with canceled context (like mongo store)
+type contextStore struct {
+	*api.MemoryUploadSessionStore
+}
+
+func (s contextStore) SetStatus(ctx context.Context, ns data.Namespace, id string, from, to api.UploadSessionStatus) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryUploadSessionStore.SetStatus(ctx, ns, id, from, to)
+}
+
+// cancelSink is upload sink canceling request on first write
+type cancelSink struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (s *cancelSink) Write(p []byte) (int, error) {
+	s.cancel()
+	return s.Buffer.Write(p)
+}
+
+func (s *cancelSink) Commit() error { return nil }
+
+func (s *cancelSink) Abort() error { return nil }
+
+func TestUploadSessions(t *testing.T) {
+	dir := t.TempDir()
+	partsDir := filepath.Join(dir, "parts")
+	store := contextStore{api.NewMemoryUploadSessionStore()}
+	sessions := api.NewUploadSessions(store, api.NewFilePartStorage(partsDir), api.UploadSessionConfig{MaxSessions: 2})
+	e := echo.New()
+	e.HTTPErrorHandler = api.NewErrorHandler().Handler
+	api.RegisterUploadSessionRoutes(e.Group("/uploads"), sessions, func(_ echo.Context, s *api.UploadSession) (api.UploadSink, error) {
+		return api.NewFileUploadSink(filepath.Join(dir, s.ID+".bin"))
+	})
+	request := func(method, path, body string, v interface{}) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if method == http.MethodPost && body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if v != nil {
+			_ = json.Unmarshal(rec.Body.Bytes(), v)
+		}
+		return rec.Code
+	}
+	create := func(content string) api.UploadSession {
+		var s api.UploadSession
+		body := fmt.Sprintf(`{"size":%d,"sha256":%q}`, len(content), data.Digest(content))
+		if code := request(http.MethodPost, "/uploads", body, &s); code != http.StatusCreated {
+			t.Fatalf("create: got status %d, expected %d", code, http.StatusCreated)
+		}
+		return s
+	}
+	uploadPart := func(s api.UploadSession, number int, content string) int {
+		return request(http.MethodPut, fmt.Sprintf("/uploads/%s/parts/%d", s.ID, number), content, nil)
+	}
+
+	t.Run("should complete upload of parts uploaded in any order", func(t *testing.T) {
+		s := create("first-second")
+		if code := uploadPart(s, 2, "second"); code != http.StatusOK {
+			t.Fatalf("got status %d, expected %d", code, http.StatusOK)
+		}
+		uploadPart(s, 1, "wrong")
+		uploadPart(s, 1, "first-")
+		var status api.UploadSession
+		request(http.MethodGet, "/uploads/"+s.ID, "", &status)
+		if len(status.Parts) != 2 || status.Parts[0].Number != 1 || status.Parts[0].SHA256 != data.Digest("first-") {
+			t.Errorf("got parts %+v", status.Parts)
+		}
+		if code := request(http.MethodPost, "/uploads/"+s.ID+"/complete", "", &status); code != http.StatusOK || status.Status != api.UploadSessionCompleted {
+			t.Fatalf("complete: got status %d session %+v", code, status)
+		}
+		if content, _ := os.ReadFile(filepath.Join(dir, s.ID+".bin")); string(content) != "first-second" {
+			t.Errorf("got content %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(partsDir, s.ID)); !os.IsNotExist(err) {
+			t.Error("expected content of parts to be removed")
+		}
+		if code := uploadPart(s, 3, "more"); code != http.StatusConflict {
+			t.Errorf("got status %d, expected %d", code, http.StatusConflict)
+		}
+	})
+	t.Run("should reject invalid content and keep session open", func(t *testing.T) {
+		s := create("abcdef")
+		uploadPart(s, 2, "def")
+		if code := request(http.MethodPost, "/uploads/"+s.ID+"/complete", "", nil); code != http.StatusBadRequest {
+			t.Errorf("missing part: got status %d, expected %d", code, http.StatusBadRequest)
+		}
+		uploadPart(s, 1, "xyz")
+		if code := request(http.MethodPost, "/uploads/"+s.ID+"/complete", "", nil); code != http.StatusBadRequest {
+			t.Errorf("digest mismatch: got status %d, expected %d", code, http.StatusBadRequest)
+		}
+		if _, err := os.Stat(filepath.Join(dir, s.ID+".bin")); !os.IsNotExist(err) {
+			t.Error("expected content of failed upload to be removed")
+		}
+		uploadPart(s, 1, "abc")
+		if code := request(http.MethodPost, "/uploads/"+s.ID+"/complete", "", nil); code != http.StatusOK {
+			t.Errorf("got status %d, expected %d", code, http.StatusOK)
+		}
+	})
+	t.Run("should limit concurrent sessions of namespace and abort session", func(t *testing.T) {
+		s1, s2 := create("one"), create("two")
+		if code := request(http.MethodPost, "/uploads", fmt.Sprintf(`{"sha256":%q}`, data.Digest("x")), nil); code != http.StatusTooManyRequests {
+			t.Errorf("got status %d, expected %d", code, http.StatusTooManyRequests)
+		}
+		uploadPart(s1, 1, "one")
+		if code := request(http.MethodDelete, "/uploads/"+s1.ID, "", nil); code != http.StatusNoContent {
+			t.Errorf("got status %d, expected %d", code, http.StatusNoContent)
+		}
+		if code := request(http.MethodGet, "/uploads/"+s1.ID, "", nil); code != http.StatusNotFound {
+			t.Errorf("got status %d, expected %d", code, http.StatusNotFound)
+		}
+		create("three")
+		request(http.MethodDelete, "/uploads/"+s2.ID, "", nil)
+	})
+	t.Run("should remove expired sessions", func(t *testing.T) {
+		s := create("expired")
+		uploadPart(s, 1, "exp")
+		removed, err := sessions.Cleanup(context.Background(), time.Now().Add(api.DefaultUploadSessionTTL+time.Minute))
+		if err != nil || removed == 0 {
+			t.Fatalf("got %d removed sessions, error %v", removed, err)
+		}
+		if _, err = store.Get(context.Background(), api.DefaultNamespaceValue, s.ID); err == nil {
+			t.Error("expected expired session to be removed")
+		}
+		if _, err = os.Stat(filepath.Join(partsDir, s.ID)); !os.IsNotExist(err) {
+			t.Error("expected content of expired session parts to be removed")
+		}
+	})
+	t.Run("should reopen session if request is canceled during completion", func(t *testing.T) {
+		s := create("canceled")
+		uploadPart(s, 1, "cance")
+		uploadPart(s, 2, "led")
+		ctx, cancel := context.WithCancel(context.Background())
+		if _, err := sessions.Complete(ctx, api.DefaultNamespaceValue, s.ID, &cancelSink{cancel: cancel}); err == nil {
+			t.Fatal("expected completion of canceled request to fail")
+		}
+		var status api.UploadSession
+		request(http.MethodGet, "/uploads/"+s.ID, "", &status)
+		if status.Status != api.UploadSessionOpen {
+			t.Errorf("got status %s, expected %s", status.Status, api.UploadSessionOpen)
+		}
+		if code := request(http.MethodDelete, "/uploads/"+s.ID, "", nil); code != http.StatusNoContent {
+			t.Errorf("got status %d, expected %d", code, http.StatusNoContent)
+		}
+	})
+	t.Run("should take over completing session with expired lease", func(t *testing.T) {
+		locked, crashed := create("locked"), create("crashed")
+		uploadPart(crashed, 1, "crashed")
+		now := time.Now()
+		if err := store.LockCompletion(context.Background(), api.DefaultNamespaceValue, locked.ID, now, now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.LockCompletion(context.Background(), api.DefaultNamespaceValue, crashed.ID, now, now); err != nil {
+			t.Fatal(err)
+		}
+		if code := request(http.MethodPost, "/uploads/"+locked.ID+"/complete", "", nil); code != http.StatusConflict {
+			t.Errorf("complete: got status %d, expected %d", code, http.StatusConflict)
+		}
+		if code := request(http.MethodDelete, "/uploads/"+locked.ID, "", nil); code != http.StatusConflict {
+			t.Errorf("abort: got status %d, expected %d", code, http.StatusConflict)
+		}
+		var status api.UploadSession
+		if code := request(http.MethodPost, "/uploads/"+crashed.ID+"/complete", "", &status); code != http.StatusOK || status.Status != api.UploadSessionCompleted {
+			t.Errorf("complete: got status %d session %+v", code, status)
+		}
+		_ = store.LockCompletion(context.Background(), api.DefaultNamespaceValue, locked.ID, now.Add(2*time.Hour), now)
+		if code := request(http.MethodDelete, "/uploads/"+locked.ID, "", nil); code != http.StatusNoContent {
+			t.Errorf("abort: got status %d, expected %d", code, http.StatusNoContent)
+		}
+	})
+	t.Run("should verify digest of part", func(t *testing.T) {
+		s := create("part")
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/uploads/%s/parts/1", s.ID), bytes.NewBufferString("part"))
+		req.Header.Set(api.HeaderContentSHA256, data.Digest("other"))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+// slowSink is upload sink delaying writes
+type slowSink struct {
+	bytes.Buffer
+	delay time.Duration
+}
+
+func (s *slowSink) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.Buffer.Write(p)
+}
+
+func (s *slowSink) Commit() error { return nil }
+
+func (s *slowSink) Abort() error { return nil }
+
+func TestUploadSessionCompleteTimeout(t *testing.T) {
+	const route = "POST /uploads/:uploadId/complete"
+	tests := []struct {
+		name     string
+		routes   map[string]time.Duration
+		complete time.Duration
+		expected int
+	}{
+		{name: "should exceed request deadline", expected: http.StatusGatewayTimeout},
+		{name: "should complete with disabled request deadline", routes: map[string]time.Duration{route: 0}, expected: http.StatusOK},
+		{name: "should limit completion by own deadline", routes: map[string]time.Duration{route: 0}, complete: 30 * time.Millisecond, expected: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := api.NewUploadSessions(api.NewMemoryUploadSessionStore(), api.NewFilePartStorage(t.TempDir()),
+				api.UploadSessionConfig{CompleteTimeout: tt.complete})
+			e := echo.New()
+			e.HTTPErrorHandler = api.NewErrorHandler().Handler
+			e.Use(api.Timeout(api.TimeoutConfig{Timeout: 50 * time.Millisecond, Routes: tt.routes}))
+			api.RegisterUploadSessionRoutes(e.Group("/uploads"), sessions, func(echo.Context, *api.UploadSession) (api.UploadSink, error) {
+				return &slowSink{delay: 20 * time.Millisecond}, nil
+			})
+			content := []string{"one", "two", "three", "four", "five"}
+			s, err := sessions.Create(context.Background(), api.DefaultNamespaceValue, api.CreateUploadSessionRequest{
+				SHA256: data.Digest(strings.Join(content, "")),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, part := range content {
+				req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/uploads/%s/parts/%d", s.ID, i+1), strings.NewReader(part))
+				e.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/uploads/"+s.ID+"/complete", nil))
+			if rec.Code != tt.expected {
+				t.Errorf("got status %d, expected %d", rec.Code, tt.expected)
+			}
+			if s, err = sessions.Get(context.Background(), api.DefaultNamespaceValue, s.ID); err != nil {
+				t.Fatal(err)
+			}
+			if tt.expected != http.StatusOK && s.Status != api.UploadSessionOpen {
+				t.Errorf("got status %s, expected %s", s.Status, api.UploadSessionOpen)
+			}
+		})
+	}
+}
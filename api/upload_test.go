@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+func TestUpload(t *testing.T) {
+	content := "firmware image content"
+	upload := func(t *testing.T, body string, header http.Header, cfg api.UploadConfig) (api.UploadResult, string, error) {
+		t.Helper()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "image.bin")
+		req := httptest.NewRequest(http.MethodPut, "/upload", strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		sink, err := api.NewFileUploadSink(path)
+		if err != nil {
+			t.Fatalf("NewFileUploadSink returned error: %v", err)
+		}
+		res, err := api.Upload(c, sink, cfg)
+		if err != nil {
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("got %d files after failed upload, expected partial data to be removed", len(entries))
+			}
+			return res, "", err
+		}
+		stored, _ := os.ReadFile(path)
+		return res, string(stored), nil
+	}
+	errorCode := func(err error) apperrors.AppErrorCode {
+		return apperrors.ToAppError(err).ErrorCode
+	}
+
+	t.Run("should store content and verify digests", func(t *testing.T) {
+		sum512 := sha512.Sum512([]byte(content))
+		header := http.Header{}
+		header.Set(echo.HeaderContentLength, "22")
+		header.Set(api.HeaderContentSHA256, strings.ToUpper(data.Digest(content)))
+		header.Set(api.HeaderContentSHA512, hex.EncodeToString(sum512[:]))
+		res, stored, err := upload(t, content, header, api.UploadConfig{RequireContentLength: true, RequireDigest: true})
+		if err != nil {
+			t.Fatalf("Upload returned error: %v", err)
+		}
+		if stored != content || res.Size != int64(len(content)) || res.SHA256 != data.Digest(content) || res.SHA512 == "" {
+			t.Errorf("got %+v stored %q", res, stored)
+		}
+	})
+	t.Run("should reject digest mismatch", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(api.HeaderContentSHA256, data.Digest("other"))
+		_, _, err := upload(t, content, header, api.UploadConfig{})
+		if errorCode(err) != apperrors.ErrorDataValidation || len(apperrors.ToAppError(err).Details) != 1 {
+			t.Errorf("got %v, expected digest mismatch error", err)
+		}
+	})
+	t.Run("should reject invalid expected digest", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(api.HeaderContentSHA256, "abc")
+		if _, _, err := upload(t, content, header, api.UploadConfig{}); errorCode(err) != apperrors.ErrorDataValidation {
+			t.Errorf("got %v, expected validation error", err)
+		}
+	})
+	t.Run("should reject body shorter than Content-Length", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(echo.HeaderContentLength, "100")
+		if _, _, err := upload(t, content, header, api.UploadConfig{}); errorCode(err) != apperrors.ErrorDataValidation {
+			t.Errorf("got %v, expected validation error", err)
+		}
+	})
+	t.Run("should reject missing Content-Length and digest", func(t *testing.T) {
+		if _, _, err := upload(t, content, http.Header{}, api.UploadConfig{RequireContentLength: true}); errorCode(err) != apperrors.ErrorDataValidation {
+			t.Errorf("got %v, expected validation error", err)
+		}
+		if _, _, err := upload(t, content, http.Header{}, api.UploadConfig{RequireDigest: true}); errorCode(err) != apperrors.ErrorDataValidation {
+			t.Errorf("got %v, expected validation error", err)
+		}
+	})
+	t.Run("should reject content above max size", func(t *testing.T) {
+		if _, _, err := upload(t, content, http.Header{}, api.UploadConfig{MaxSize: 10}); errorCode(err) != apperrors.ErrorDataTooLarge {
+			t.Errorf("got %v, expected %s", err, apperrors.ErrorDataTooLarge)
+		}
+	})
+}
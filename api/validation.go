@@ -0,0 +1,267 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// ValidateTag is struct tag name with comma separated list of validation rules, supported rules:
+	//   required      - value should not be zero value (nil or empty for pointers, slices and maps)
+	//   len:N         - length of string (in runes), slice or map should be equal N
+	//   len:MIN:MAX   - length of string (in runes), slice or map should be in range [MIN, MAX]
+	//   hex[:N]       - lower case hex string (of length N)
+	//   base64        - base64 encoded string
+	//   uuid          - string representation of UUID
+	//   namespace     - valid OTA namespace (see data.ValidNamespace)
+	//   enum:A|B|C    - value should be one of listed
+	// rules except required are skipped for zero values
+	ValidateTag = "validate"
+
+	ruleRequired  = "required"
+	ruleLen       = "len"
+	ruleHex       = "hex"
+	ruleBase64    = "base64"
+	ruleUUID      = "uuid"
+	ruleNamespace = "namespace"
+	ruleEnum      = "enum"
+
+	ruleArgSeparator  = ":"
+	ruleEnumSeparator = "|"
+)
+
+type (
+	// validationRule checks value, it returns error message if value is not valid
+	validationRule func(reflect.Value) string
+
+	// fieldValidation is a set of validation rules of struct field
+	fieldValidation struct {
+		index    int
+		name     string
+		required bool
+		rules    []validationRule
+	}
+)
+
+// validationCache caches parsed validation rules by struct type
+var validationCache sync.Map
+
+// Validate validates struct using ValidateTag struct field tags,
+// it returns AppError with ErrorDataValidation code and per-field details
+func Validate(v interface{}) error {
+	var details []apperrors.FieldError
+	if err := validateValue(reflect.ValueOf(v), "", &details); err != nil {
+		return err
+	}
+	if len(details) > 0 {
+		return apperrors.NewValidationError(
+			fmt.Sprintf("validation failed for %d field(s)", len(details)), details)
+	}
+	return nil
+}
+
+// validateValue recursively validates structs, pointers to structs and slices of structs
+func validateValue(val reflect.Value, path string, details *[]apperrors.FieldError) error {
+	switch val.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+		return validateValue(val.Elem(), path, details)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := validateValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), details); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	fields, err := structValidation(val.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := val.Field(f.index)
+		name := f.name
+		if path != "" {
+			name = path + "." + f.name
+		}
+		if isEmptyValue(fv) {
+			if f.required {
+				*details = append(*details, apperrors.FieldError{Field: name, Message: "is required"})
+			}
+			continue
+		}
+		valid := true
+		for _, rule := range f.rules {
+			if msg := rule(fv); msg != "" {
+				*details = append(*details, apperrors.FieldError{Field: name, Message: msg})
+				valid = false
+				break
+			}
+		}
+		if valid {
+			if err = validateValue(fv, name, details); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structValidation returns validation rules of struct type
+func structValidation(t reflect.Type) ([]fieldValidation, error) {
+	if cached, ok := validationCache.Load(t); ok {
+		return cached.([]fieldValidation), nil
+	}
+	var fields []fieldValidation
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := fieldValidation{index: i, name: jsonFieldName(sf)}
+		if f.name == "-" {
+			continue
+		}
+		tag := sf.Tag.Get(ValidateTag)
+		if tag != "" {
+			for _, item := range strings.Split(tag, ",") {
+				if item == ruleRequired {
+					f.required = true
+					continue
+				}
+				rule, err := parseRule(item, sf.Type)
+				if err != nil {
+					return nil, apperrors.NewAppError(apperrors.ErrorGeneric,
+						fmt.Sprintf("invalid validation rule %q of field %s.%s: %v", item, t.Name(), sf.Name, err))
+				}
+				f.rules = append(f.rules, rule)
+			}
+		}
+		fields = append(fields, f)
+	}
+	validationCache.Store(t, fields)
+	return fields, nil
+}
+
+// parseRule creates validation rule from its tag definition
+func parseRule(def string, t reflect.Type) (validationRule, error) {
+	name, arg, _ := strings.Cut(def, ruleArgSeparator)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name != ruleLen && t.Kind() != reflect.String {
+		return nil, fmt.Errorf("rule is supported only for string fields")
+	}
+	switch name {
+	case ruleLen:
+		return lenRule(arg, t)
+	case ruleHex:
+		return hexRule(arg)
+	case ruleBase64:
+		return stringRule(data.ValidBase64, "should be base64 encoded"), nil
+	case ruleUUID:
+		return stringRule(func(s string) bool {
+			_, err := data.CorrelationIDFromString(s)
+			return err == nil
+		}, "should be UUID"), nil
+	case ruleNamespace:
+		return stringRule(data.ValidNamespace, "should be valid namespace"), nil
+	case ruleEnum:
+		values := strings.Split(arg, ruleEnumSeparator)
+		return stringRule(func(s string) bool {
+			return contains(values, s)
+		}, fmt.Sprintf("should be one of [%s]", strings.Join(values, ", "))), nil
+	}
+	return nil, fmt.Errorf("unknown rule")
+}
+
+func lenRule(arg string, t reflect.Type) (validationRule, error) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+	default:
+		return nil, fmt.Errorf("rule is supported only for string, slice and map fields")
+	}
+	minArg, maxArg, isRange := strings.Cut(arg, ruleArgSeparator)
+	if !isRange {
+		maxArg = minArg
+	}
+	lower, err := strconv.Atoi(minArg)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := strconv.Atoi(maxArg)
+	if err != nil {
+		return nil, err
+	}
+	msg := fmt.Sprintf("length should be %d", lower)
+	if lower != upper {
+		msg = fmt.Sprintf("length should be in range [%d, %d]", lower, upper)
+	}
+	return func(v reflect.Value) string {
+		v = reflect.Indirect(v)
+		l := v.Len()
+		if v.Kind() == reflect.String {
+			l = utf8.RuneCountInString(v.String())
+		}
+		if l < lower || l > upper {
+			return msg
+		}
+		return ""
+	}, nil
+}
+
+func hexRule(arg string) (validationRule, error) {
+	if arg == "" {
+		return stringRule(func(s string) bool {
+			return data.ValidHex(len(s), s)
+		}, "should be hex string"), nil
+	}
+	length, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, err
+	}
+	return stringRule(func(s string) bool {
+		return data.ValidHex(length, s)
+	}, fmt.Sprintf("should be hex string of length %d", length)), nil
+}
+
+// stringRule creates validation rule from string validation func
+func stringRule(valid func(string) bool, msg string) validationRule {
+	return func(v reflect.Value) string {
+		if !valid(reflect.Indirect(v).String()) {
+			return msg
+		}
+		return ""
+	}
+}
+
+// isEmptyValue reports if value is zero value, nil or empty slice or map
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// jsonFieldName returns field name used in json serialization
+func jsonFieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
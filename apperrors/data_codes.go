@@ -5,4 +5,8 @@ const (
 	ErrorDataSerialization = ErrorNamespaceData + ":Serialization"
 	// ErrorDataValidation is error type returned if data.Ref is not pass validation
 	ErrorDataValidation = ErrorNamespaceData + ":Validation"
+	// ErrorDataTooLarge is error type returned if request payload exceeds allowed size
+	ErrorDataTooLarge = ErrorNamespaceData + ":TooLarge"
+	// ErrorDataRangeNotSatisfiable is error type returned if requested byte ranges are outside of content
+	ErrorDataRangeNotSatisfiable = ErrorNamespaceData + ":RangeNotSatisfiable"
 )
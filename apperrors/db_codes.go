@@ -9,4 +9,8 @@ const (
 	ErrorDbNoDocumentFound = ErrorNamespaceDB + ":DocumentNotFound"
 	// ErrorDbAlreadyExist is error type returned on creation of document if document with doc id already exists
 	ErrorDbAlreadyExist = ErrorNamespaceDB + ":DocumentAlreadyExist"
+	// ErrorDbVersionConflict is error type returned on update of document if its version does not match expected one
+	ErrorDbVersionConflict = ErrorNamespaceDB + ":VersionConflict"
+	// ErrorDbTimeout is error type returned if db operation was not completed in time (Db.Timeout())
+	ErrorDbTimeout = ErrorNamespaceDB + ":Timeout"
 )
@@ -17,6 +17,16 @@ type AppError struct {
 	ErrorCode AppErrorCode `json:"error_code"`
 	// Description description of error
 	Description string `json:"description"`
+	// Details per-field validation errors
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError is validation error of a single field
+type FieldError struct {
+	// Field path of field (ex. "targets[0].hash")
+	Field string `json:"field"`
+	// Message description of validation error
+	Message string `json:"message"`
 }
 
 func (err AppError) Error() string {
@@ -31,6 +41,15 @@ func NewAppError(code AppErrorCode, descr string) error {
 	}
 }
 
+// NewValidationError creates new AppError with ErrorDataValidation code and per-field details
+func NewValidationError(descr string, details []FieldError) error {
+	return AppError{
+		ErrorCode:   ErrorDataValidation,
+		Description: descr,
+		Details:     details,
+	}
+}
+
 // CreateError create new AppError
 func CreateError(code AppErrorCode, descr string, err error) error {
 	return NewAppError(code, fmt.Sprintf("%s (%v)", descr, err))
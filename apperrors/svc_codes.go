@@ -3,4 +3,24 @@ package apperrors
 const (
 	// ErrorSvcEntityExists is error to incorrect user operation( try accidentally replacing some entity)
 	ErrorSvcEntityExists = ErrorNamespaceSvc + ":EntityAlreadyExist"
+	// ErrorSvcWarmup is error type returned if service startup warm-up hook failed
+	ErrorSvcWarmup = ErrorNamespaceSvc + ":WarmupFailed"
+	// ErrorSvcShutdown is error type returned if service graceful shutdown failed
+	ErrorSvcShutdown = ErrorNamespaceSvc + ":ShutdownFailed"
+	// ErrorSvcPreconditionFailed is error type returned if conditional request precondition (If-Match/If-None-Match) is false
+	ErrorSvcPreconditionFailed = ErrorNamespaceSvc + ":PreconditionFailed"
+	// ErrorSvcIdempotencyKeyReused is error type returned if idempotency key is reused with different request
+	ErrorSvcIdempotencyKeyReused = ErrorNamespaceSvc + ":IdempotencyKeyReused"
+	// ErrorSvcIdempotencyInProgress is error type returned if request with the same idempotency key is still processed
+	ErrorSvcIdempotencyInProgress = ErrorNamespaceSvc + ":IdempotencyInProgress"
+	// ErrorSvcRateLimited is error type returned if request rate limit is exceeded
+	ErrorSvcRateLimited = ErrorNamespaceSvc + ":RateLimited"
+	// ErrorSvcOverloaded is error type returned if request is rejected because of server overload
+	ErrorSvcOverloaded = ErrorNamespaceSvc + ":Overloaded"
+	// ErrorSvcTimeout is error type returned if request was not handled in time
+	ErrorSvcTimeout = ErrorNamespaceSvc + ":Timeout"
+	// ErrorSvcQuotaExceeded is error type returned if namespace quota of resources is exceeded
+	ErrorSvcQuotaExceeded = ErrorNamespaceSvc + ":QuotaExceeded"
+	// ErrorSvcInvalidState is error type returned if operation is not allowed in current state of entity
+	ErrorSvcInvalidState = ErrorNamespaceSvc + ":InvalidState"
 )
@@ -0,0 +1,15 @@
+/*
+Package config fills typed service configuration from defaults, files, mounted secrets,
+environment variables and command-line flags.
+
+Sources are applied in order of precedence (later source overrides earlier one):
+  - `default:"value"` struct tag
+  - YAML (.yaml, .yml) or JSON (.json) files
+  - secret files: `secret:"name"` struct tag is file name in secrets directory
+  - environment variables: `env:"NAME"` struct tag, NAME_FILE variable points to file with value
+  - command-line flags: `flag:"name"` struct tag
+
+Field marked with `required:"true"` tag should be non-zero after all sources are applied.
+`env` and `flag` tags of nested struct are used as prefix of its fields names.
+*/
+package config
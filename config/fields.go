@@ -0,0 +1,268 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tagEnv      = "env"
+	tagFlag     = "flag"
+	tagSecret   = "secret"
+	tagDefault  = "default"
+	tagRequired = "required"
+	tagUsage    = "usage"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// field is configurable leaf field of config struct
+type field struct {
+	value    reflect.Value
+	path     string
+	env      string
+	flag     string
+	secret   string
+	def      string
+	usage    string
+	required bool
+}
+
+// collectFields returns leaf fields of struct, nested structs are walked recursively
+func collectFields(rv reflect.Value, path, envPrefix, flagPrefix string) []field {
+	var fields []field
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		fpath := joinName(path, sf.Name, ".")
+		env := joinName(envPrefix, sf.Tag.Get(tagEnv), "_")
+		flg := joinName(flagPrefix, sf.Tag.Get(tagFlag), "-")
+		if sf.Tag.Get(tagEnv) == "" {
+			env = ""
+		}
+		if sf.Tag.Get(tagFlag) == "" {
+			flg = ""
+		}
+
+		if isNested(fv) {
+			fields = append(fields, collectFields(fv, fpath,
+				prefixOrParent(env, envPrefix), prefixOrParent(flg, flagPrefix))...)
+			continue
+		}
+		fields = append(fields, field{
+			value:    fv,
+			path:     fpath,
+			env:      env,
+			flag:     flg,
+			secret:   sf.Tag.Get(tagSecret),
+			def:      sf.Tag.Get(tagDefault),
+			usage:    sf.Tag.Get(tagUsage),
+			required: sf.Tag.Get(tagRequired) == "true",
+		})
+	}
+	return fields
+}
+
+func isNested(fv reflect.Value) bool {
+	return fv.Kind() == reflect.Struct &&
+		!reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType)
+}
+
+func joinName(prefix, name, sep string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + sep + name
+}
+
+func prefixOrParent(prefix, parent string) string {
+	if prefix == "" {
+		return parent
+	}
+	return prefix
+}
+
+// setValue parses string and assigns it to field value
+func setValue(fv reflect.Value, s string) error {
+	if reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(fv.Type(), 0, len(parts))
+		for _, p := range parts {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			ev := reflect.New(fv.Type().Elem()).Elem()
+			if err := setValue(ev, p); err != nil {
+				return err
+			}
+			sl = reflect.Append(sl, ev)
+		}
+		fv.Set(sl)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// jsonDurations replaces duration strings (ex. "5s") of JSON value decoded for type t with nanoseconds,
+// so encoding/json can decode them into time.Duration fields
+func jsonDurations(raw interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		if s, ok := raw.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, err
+			}
+			return int64(d), nil
+		}
+	case t.Kind() == reflect.Struct:
+		if obj, ok := raw.(map[string]interface{}); ok {
+			return obj, jsonStructDurations(obj, t)
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if arr, ok := raw.([]interface{}); ok {
+			for i := range arr {
+				v, err := jsonDurations(arr[i], t.Elem())
+				if err != nil {
+					return nil, err
+				}
+				arr[i] = v
+			}
+		}
+	case t.Kind() == reflect.Map:
+		if obj, ok := raw.(map[string]interface{}); ok {
+			for k := range obj {
+				v, err := jsonDurations(obj[k], t.Elem())
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+				obj[k] = v
+			}
+		}
+	}
+	return raw, nil
+}
+
+// jsonStructDurations converts duration strings of JSON object decoded for struct type t
+func jsonStructDurations(obj map[string]interface{}, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// fields of embedded struct are inlined
+			if err := jsonStructDurations(obj, ft); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		for key, val := range obj {
+			// encoding/json matches keys case-insensitively
+			if key != name && !strings.EqualFold(key, name) {
+				continue
+			}
+			v, err := jsonDurations(val, sf.Type)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			obj[key] = v
+		}
+	}
+	return nil
+}
+
+// validate runs Validate of config struct and its nested structs,
+// errors of Validate methods promoted from embedded structs are reported once
+func validate(rv reflect.Value) []string {
+	var errs []string
+	seen := make(map[string]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		if v.CanAddr() {
+			if val, ok := v.Addr().Interface().(Validator); ok {
+				if err := val.Validate(); err != nil && !seen[err.Error()] {
+					seen[err.Error()] = true
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			fv := v.Field(i)
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if isNested(fv) {
+				walk(fv)
+			}
+		}
+	}
+	walk(rv)
+	return errs
+}
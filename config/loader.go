@@ -0,0 +1,264 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// fileEnvSuffix is suffix of environment variable pointing to file with value
+const fileEnvSuffix = "_FILE"
+
+// Validator is implemented by config structs with custom validation rules,
+// it is run for config struct and all its nested sections
+type Validator interface {
+	// Validate returns error if configuration is not valid
+	Validate() error
+}
+
+// Option configures config Load
+type Option func(*loader)
+
+type loader struct {
+	files      []string
+	secretsDir string
+	envPrefix  string
+	args       []string
+	flagSet    string
+	lookupEnv  func(string) (string, bool)
+}
+
+// WithFiles adds YAML or JSON files applied in provided order
+func WithFiles(paths ...string) Option {
+	return func(l *loader) {
+		l.files = append(l.files, paths...)
+	}
+}
+
+// WithSecretsDir sets directory of mounted secret files (ex. k8s Secret volume)
+func WithSecretsDir(dir string) Option {
+	return func(l *loader) {
+		l.secretsDir = dir
+	}
+}
+
+// WithEnvPrefix sets prefix of all environment variables names
+func WithEnvPrefix(prefix string) Option {
+	return func(l *loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithFlags enables command-line flags parsing of provided arguments (ex. os.Args[1:])
+func WithFlags(name string, args []string) Option {
+	return func(l *loader) {
+		l.flagSet = name
+		l.args = args
+	}
+}
+
+// WithEnvLookup replaces os.LookupEnv used to read environment variables
+func WithEnvLookup(lookup func(string) (string, bool)) Option {
+	return func(l *loader) {
+		l.lookupEnv = lookup
+	}
+}
+
+// Load fills cfg (pointer to struct) from all configured sources
+func Load(cfg interface{}, opts ...Option) error {
+	return newLoader(opts...).load(cfg)
+}
+
+func newLoader(opts ...Option) *loader {
+	l := loader{lookupEnv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	return &l
+}
+
+func (l *loader) load(cfg interface{}) error {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			"config should be pointer to struct")
+	}
+	fields := collectFields(rv.Elem(), "", l.envPrefix, "")
+
+	var errs []string
+	set := func(f field, val, source string) {
+		if err := setValue(f.value, val); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid value from %s (%v)", f.path, source, err))
+		}
+	}
+
+	for _, f := range fields {
+		if f.def != "" {
+			set(f, f.def, "default")
+		}
+	}
+	for _, path := range l.files {
+		if err := decodeFile(path, cfg); err != nil {
+			return err
+		}
+	}
+	for _, f := range fields {
+		if f.secret == "" || l.secretsDir == "" {
+			continue
+		}
+		val, found, err := readSecret(filepath.Join(l.secretsDir, f.secret))
+		if err != nil {
+			return err
+		}
+		if found {
+			set(f, val, "secret "+f.secret)
+		}
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if val, ok := l.lookupEnv(f.env); ok {
+			set(f, val, "environment variable "+f.env)
+			continue
+		}
+		if path, ok := l.lookupEnv(f.env + fileEnvSuffix); ok {
+			val, found, err := readSecret(path)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return apperrors.NewAppError(apperrors.ErrorFsPath,
+					fmt.Sprintf("file %s from environment variable %s not found", path, f.env+fileEnvSuffix))
+			}
+			set(f, val, "file "+path)
+		}
+	}
+	if l.args != nil {
+		if err := l.parseFlags(fields, set); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		if f.required && f.value.IsZero() {
+			errs = append(errs, fmt.Sprintf("%s: is required", f.path))
+		}
+	}
+	if len(errs) == 0 {
+		errs = validate(rv.Elem())
+	}
+	if len(errs) > 0 {
+		return apperrors.NewAppError(apperrors.ErrorDataValidation,
+			"invalid configuration: "+strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (l *loader) parseFlags(fields []field, set func(field, string, string)) error {
+	fs := flag.NewFlagSet(l.flagSet, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	byName := make(map[string]field)
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		byName[f.flag] = f
+		fs.String(f.flag, f.def, f.usage)
+	}
+	if err := fs.Parse(l.args); err != nil {
+		return apperrors.CreateError(apperrors.ErrorDataValidation, "failed to parse command-line flags", err)
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		set(byName[fl.Name], fl.Value.String(), "flag -"+fl.Name)
+	})
+	return nil
+}
+
+// watchPaths returns config files, secrets directory and files from NAME_FILE variables
+func (l *loader) watchPaths(cfg interface{}) []string {
+	paths := append([]string(nil), l.files...)
+	if l.secretsDir != "" {
+		paths = append(paths, l.secretsDir)
+	}
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return paths
+	}
+	for _, f := range collectFields(rv.Elem(), "", l.envPrefix, "") {
+		if f.env == "" {
+			continue
+		}
+		if path, ok := l.lookupEnv(f.env + fileEnvSuffix); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// decodeFile decodes YAML or JSON file (by extension) into cfg
+func decodeFile(path string, cfg interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return apperrors.CreateError(apperrors.ErrorFsPath, "config file not found", err)
+		}
+		return apperrors.CreateError(apperrors.ErrorFsIOOpen, "failed to read config file", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = decodeJSON(b, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, cfg)
+	default:
+		return apperrors.NewAppError(apperrors.ErrorDataSerialization,
+			fmt.Sprintf("unsupported config file format %s", path))
+	}
+	if err != nil {
+		return apperrors.CreateError(apperrors.ErrorDataSerialization,
+			fmt.Sprintf("failed to decode config file %s", path), err)
+	}
+	return nil
+}
+
+// decodeJSON decodes JSON into cfg, duration fields accept strings (ex. "5s") as well as nanoseconds
+func decodeJSON(b []byte, cfg interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// keep numbers exact when document is encoded again
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	raw, err := jsonDurations(raw, reflect.TypeOf(cfg))
+	if err != nil {
+		return err
+	}
+	if b, err = json.Marshal(raw); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, cfg)
+}
+
+// readSecret returns content of secret file without trailing new line
+func readSecret(path string) (string, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, apperrors.CreateError(apperrors.ErrorFsIOOpen, "failed to read secret file", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), true, nil
+}
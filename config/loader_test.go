@@ -0,0 +1,168 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/config"
+)
+
+type testConfig struct {
+	config.ServiceConfig `yaml:",inline"`
+	Workers              int      `yaml:"workers" env:"WORKERS" flag:"workers" default:"2"`
+	Tags                 []string `yaml:"tags" env:"TAGS"`
+}
+
+type limitsConfig struct {
+	Window time.Duration `json:"window"`
+	Max    int           `json:"max"`
+}
+
+func (c limitsConfig) Validate() error {
+	if c.Max < 0 {
+		return errors.New("limits.max: should not be negative")
+	}
+	return nil
+}
+
+type jsonConfig struct {
+	config.ServiceConfig
+	Limits   limitsConfig             `json:"limits"`
+	Backoffs []time.Duration          `json:"backoffs"`
+	Routes   map[string]time.Duration `json:"routes"`
+}
+
+func envLookup(env map[string]string) config.Option {
+	return config.WithEnvLookup(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile returned error: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should apply sources in order of precedence", func(t *testing.T) {
+		dir := t.TempDir()
+		file := writeFile(t, dir, "config.yaml", `
+server:
+  name: from-file
+  version: "1.0"
+workers: 4
+mongo:
+  connectionString: mongodb://file/db
+`)
+		var cfg testConfig
+		err := config.Load(&cfg,
+			config.WithFiles(file),
+			config.WithEnvPrefix("OTA"),
+			envLookup(map[string]string{
+				"OTA_WORKERS":       "8",
+				"OTA_TAGS":          "a, b",
+				"OTA_MONGO_TIMEOUT": "10s",
+			}),
+			config.WithFlags("test", []string{"-workers", "16", "-server-name", "from-flag"}))
+		if err != nil {
+			t.Fatalf("Load returned error: %v", err)
+		}
+		if cfg.Server.Name != "from-flag" || cfg.Server.Version != "1.0" {
+			t.Errorf("got server %+v, expected name from flag and version from file", cfg.Server)
+		}
+		if cfg.Workers != 16 {
+			t.Errorf("got %d workers, expected value from flag", cfg.Workers)
+		}
+		if cfg.Mongo.Timeout != 10*time.Second || cfg.Mongo.ConnectionString != "mongodb://file/db" {
+			t.Errorf("got mongo %+v, expected timeout from env and connection string from file", cfg.Mongo)
+		}
+		if len(cfg.Tags) != 2 || cfg.Tags[1] != "b" {
+			t.Errorf("got tags %v, expected [a b]", cfg.Tags)
+		}
+		if cfg.Listen.Public != ":8080" || cfg.Log.Level != "info" {
+			t.Errorf("defaults were not applied: %+v %+v", cfg.Listen, cfg.Log)
+		}
+	})
+	t.Run("should read secrets from mounted files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "mongo-connection-string", "mongodb://secret/db\n")
+		pwd := writeFile(t, dir, "name", "from-env-file\n")
+		var cfg testConfig
+		err := config.Load(&cfg,
+			config.WithSecretsDir(dir),
+			envLookup(map[string]string{"SERVER_NAME_FILE": pwd}))
+		if err != nil {
+			t.Fatalf("Load returned error: %v", err)
+		}
+		if cfg.Mongo.ConnectionString != "mongodb://secret/db" || cfg.Server.Name != "from-env-file" {
+			t.Errorf("got %+v, expected values from secret files", cfg.ServiceConfig)
+		}
+	})
+	t.Run("should report validation errors as ErrorDataValidation", func(t *testing.T) {
+		var cfg testConfig
+		err := config.Load(&cfg, envLookup(map[string]string{"WORKERS": "many"}))
+		var typedErr apperrors.AppError
+		if !errors.As(err, &typedErr) || typedErr.ErrorCode != apperrors.ErrorDataValidation {
+			t.Fatalf("got %v, expected %s", err, apperrors.ErrorDataValidation)
+		}
+	})
+	t.Run("should validate log level", func(t *testing.T) {
+		var cfg testConfig
+		err := config.Load(&cfg, envLookup(map[string]string{
+			"SERVER_NAME":             "svc",
+			"MONGO_CONNECTION_STRING": "mongodb://localhost/db",
+			"LOG_LEVEL":               "verbose",
+		}))
+		if err == nil {
+			t.Error("Load should fail on unknown log level")
+		}
+	})
+	t.Run("should decode duration strings of JSON file", func(t *testing.T) {
+		file := writeFile(t, t.TempDir(), "config.json", `{
+  "server": {"name": "svc"},
+  "mongo": {"connectionString": "mongodb://localhost/db", "timeout": "5s"},
+  "limits": {"window": "1m", "max": 10},
+  "backoffs": ["100ms", 1000000000],
+  "routes": {"GET /events": "0s"}
+}`)
+		var cfg jsonConfig
+		if err := config.Load(&cfg, config.WithFiles(file)); err != nil {
+			t.Fatalf("Load returned error: %v", err)
+		}
+		if cfg.Mongo.Timeout != 5*time.Second || cfg.Limits.Window != time.Minute || cfg.Limits.Max != 10 {
+			t.Errorf("got mongo %+v and limits %+v", cfg.Mongo, cfg.Limits)
+		}
+		if len(cfg.Backoffs) != 2 || cfg.Backoffs[0] != 100*time.Millisecond || cfg.Backoffs[1] != time.Second {
+			t.Errorf("got backoffs %v", cfg.Backoffs)
+		}
+		if d, ok := cfg.Routes["GET /events"]; !ok || d != 0 {
+			t.Errorf("got routes %v", cfg.Routes)
+		}
+	})
+	t.Run("should validate nested sections", func(t *testing.T) {
+		file := writeFile(t, t.TempDir(), "config.json", `{
+  "server": {"name": "svc"},
+  "mongo": {"connectionString": "mongodb://localhost/db"},
+  "log": {"level": "verbose"},
+  "limits": {"max": -1}
+}`)
+		var cfg jsonConfig
+		err := config.Load(&cfg, config.WithFiles(file))
+		if err == nil {
+			t.Fatal("Load should fail on invalid nested section")
+		}
+		msg := err.Error()
+		if !strings.Contains(msg, "limits.max") || strings.Count(msg, "log.level") != 1 {
+			t.Errorf("got error %q, expected each section error once", msg)
+		}
+	})
+}
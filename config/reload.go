@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// ReloadMongo applies changes of selected MongoConfig to db:
+// new operation timeout is applied first, db is reconnected when connection string changes
+func ReloadMongo[T any](ctx context.Context, w *Watcher[T], selector func(*T) MongoConfig, db *mongo.Db) {
+	OnChange(w, selector, func(prev, next MongoConfig) {
+		if prev.Timeout != next.Timeout && next.Timeout > 0 {
+			db.SetTimeout(next.Timeout)
+			w.log.WithField("timeout", next.Timeout).Info("DB operation timeout changed")
+		}
+		if prev.ConnectionString != next.ConnectionString {
+			if err := db.Reconnect(ctx, next.ConnectionString); err != nil {
+				w.log.WithError(err).Error("Failed to apply new DB connection string")
+			}
+		}
+	})
+}
+
+// ReloadLogLevel changes logger level when selected LogConfig changes
+func ReloadLogLevel[T any](w *Watcher[T], selector func(*T) LogConfig, log logger.Logger) {
+	OnChange(w, selector, func(_, cfg LogConfig) {
+		if err := cfg.Apply(log); err != nil {
+			w.log.WithError(err).Error("Failed to apply new log level")
+			return
+		}
+		w.log.WithField("level", cfg.Level).Info("Log level changed")
+	})
+}
@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/shuvava/go-ota-svc-common/config"
+)
+
+func TestReloadMongo(t *testing.T) {
+	t.Run("should apply new timeout and keep connection if new one is unreachable", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeFile(t, dir, "config.yaml", `
+server:
+  name: svc
+mongo:
+  connectionString: mongodb://127.0.0.1:1/db
+  timeout: 5s
+`)
+		log := logger.NewNopLogger()
+		w, err := config.NewWatcher[testConfig](log, config.WithFiles(path))
+		if err != nil {
+			t.Fatalf("NewWatcher returned error: %v", err)
+		}
+		ctx := context.Background()
+		// mongo client connects lazily, so unreachable server can be used
+		db, err := w.Current().Mongo.Connect(ctx, log)
+		if err != nil {
+			t.Fatalf("Connect returned error: %v", err)
+		}
+		defer func() { _ = db.Disconnect(ctx) }()
+		client := db.Database().Client()
+		config.ReloadMongo(ctx, w, func(c *testConfig) config.MongoConfig { return c.Mongo }, db)
+
+		writeFile(t, dir, "config.yaml", `
+server:
+  name: svc
+mongo:
+  connectionString: mongodb://127.0.0.1:2/db
+  timeout: 50ms
+`)
+		start := time.Now()
+		if err = w.Reload(); err != nil {
+			t.Fatalf("Reload returned error: %v", err)
+		}
+		if db.Database().Client() != client {
+			t.Error("connection should not be replaced by unreachable one")
+		}
+		if _, err = db.Count(ctx, db.GetCollection("items"), bson.D{}); err == nil {
+			t.Fatal("Count should fail on unreachable server")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("reload and operation took %s, expected new timeout to be applied", elapsed)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/api"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+// ServiceConfig is standard configuration of OTA service,
+// it can be embedded into service specific config struct
+type ServiceConfig struct {
+	Server ServerConfig `yaml:"server" json:"server" env:"SERVER" flag:"server"`
+	Listen ListenConfig `yaml:"listen" json:"listen" env:"LISTEN" flag:"listen"`
+	Log    LogConfig    `yaml:"log" json:"log" env:"LOG" flag:"log"`
+	Mongo  MongoConfig  `yaml:"mongo" json:"mongo" env:"MONGO" flag:"mongo"`
+}
+
+// ServerConfig is service name and version reported by api.ServerHeader
+type ServerConfig struct {
+	Name    string `yaml:"name" json:"name" env:"NAME" flag:"name" required:"true" usage:"service name"`
+	Version string `yaml:"version" json:"version" env:"VERSION" flag:"version" default:"dev" usage:"service version"`
+}
+
+// ListenConfig is addresses of service listeners
+type ListenConfig struct {
+	Public string `yaml:"public" json:"public" env:"PUBLIC" flag:"public" default:":8080" usage:"public listener address"`
+	Admin  string `yaml:"admin" json:"admin" env:"ADMIN" flag:"admin" usage:"admin listener address"`
+}
+
+// ServerOptions returns api.Server options of listeners
+func (c ListenConfig) ServerOptions() []api.ServerOption {
+	return []api.ServerOption{
+		api.WithPublicAddress(c.Public),
+		api.WithAdminAddress(c.Admin),
+	}
+}
+
+// LogConfig is logger configuration
+type LogConfig struct {
+	Level string `yaml:"level" json:"level" env:"LEVEL" flag:"level" default:"info" usage:"log level"`
+}
+
+// Validate checks if log level is known
+func (c LogConfig) Validate() error {
+	if logger.ParseLevel(logger.ToLogLevel(c.Level)) != strings.ToLower(c.Level) {
+		return fmt.Errorf("log.level: unknown level %s", c.Level)
+	}
+	return nil
+}
+
+// Apply sets logger level
+func (c LogConfig) Apply(log logger.Logger) error {
+	return log.SetLevel(logger.ToLogLevel(c.Level))
+}
+
+// MongoConfig is MongoDb connection configuration
+type MongoConfig struct {
+	ConnectionString string        `yaml:"connectionString" json:"connectionString" env:"CONNECTION_STRING" flag:"connection-string" secret:"mongo-connection-string" required:"true" usage:"mongodb connection string"`
+	Timeout          time.Duration `yaml:"timeout" json:"timeout" env:"TIMEOUT" flag:"timeout" default:"5s" usage:"mongodb operation timeout"`
+}
+
+// Connect creates mongo.Db connection with configured operation timeout
+func (c MongoConfig) Connect(ctx context.Context, log logger.Logger) (*mongo.Db, error) {
+	db, err := mongo.NewMongoDB(ctx, log, c.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	db.SetTimeout(c.Timeout)
+	return db, nil
+}
+
+// Validate checks standard configuration sections
+func (c ServiceConfig) Validate() error {
+	return c.Log.Validate()
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// DefaultReloadDelay is default time to collect file events before configuration reload
+const DefaultReloadDelay = 200 * time.Millisecond
+
+// ChangeEvent is configuration change event
+type ChangeEvent[T any] struct {
+	// Old is configuration before change
+	Old *T
+	// New is configuration after change
+	New *T
+}
+
+// Watcher reloads configuration on changes of config files and mounted secrets
+// (k8s ConfigMap and Secret volumes are updated through symlink swap of whole directory)
+type Watcher[T any] struct {
+	loader  *loader
+	log     logger.Logger
+	current atomic.Pointer[T]
+	mu      sync.RWMutex
+	subs    []func(ChangeEvent[T])
+	// ReloadDelay is time to collect file events before configuration reload
+	ReloadDelay time.Duration
+}
+
+// NewWatcher loads configuration and creates Watcher of its sources
+func NewWatcher[T any](lgr logger.Logger, opts ...Option) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		loader:      newLoader(opts...),
+		log:         lgr.SetOperation("ConfigWatcher"),
+		ReloadDelay: DefaultReloadDelay,
+	}
+	cfg := new(T)
+	if err := w.loader.load(cfg); err != nil {
+		return nil, err
+	}
+	w.current.Store(cfg)
+	return w, nil
+}
+
+// Current returns current configuration, returned value should not be modified
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe adds handler of configuration changes
+func (w *Watcher[T]) Subscribe(fn func(ChangeEvent[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// OnChange subscribes fn called when section selected from configuration changed
+func OnChange[T, S any](w *Watcher[T], selector func(*T) S, fn func(prev, next S)) {
+	w.Subscribe(func(ev ChangeEvent[T]) {
+		prev, next := selector(ev.Old), selector(ev.New)
+		if !reflect.DeepEqual(prev, next) {
+			fn(prev, next)
+		}
+	})
+}
+
+// Reload loads configuration and notifies subscribers if it changed,
+// current configuration is kept if new one is not valid
+func (w *Watcher[T]) Reload() error {
+	cfg := new(T)
+	if err := w.loader.load(cfg); err != nil {
+		return err
+	}
+	old := w.current.Load()
+	if reflect.DeepEqual(old, cfg) {
+		return nil
+	}
+	w.current.Store(cfg)
+	w.log.Info("Configuration changed")
+
+	w.mu.RLock()
+	subs := make([]func(ChangeEvent[T]), len(w.subs))
+	copy(subs, w.subs)
+	w.mu.RUnlock()
+	for _, fn := range subs {
+		fn(ChangeEvent[T]{Old: old, New: cfg})
+	}
+	return nil
+}
+
+// Run watches configuration sources until ctx is canceled
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return apperrors.CreateErrorAndLogIt(w.log,
+			apperrors.ErrorFsIOOperation,
+			"Failed to create file watcher", err)
+	}
+	defer fw.Close()
+	for _, dir := range watchDirs(w.loader.watchPaths(w.Current())) {
+		if err = fw.Add(dir); err != nil {
+			return apperrors.CreateErrorAndLogIt(w.log.WithField("path", dir),
+				apperrors.ErrorFsPath,
+				"Failed to watch configuration directory", err)
+		}
+	}
+
+	timer := time.NewTimer(w.ReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			w.log.WithField("event", ev.String()).Debug("Configuration source changed")
+			timer.Reset(w.ReloadDelay)
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.log.WithError(err).Warn("Configuration watcher error")
+		case <-timer.C:
+			if err = w.Reload(); err != nil {
+				w.log.WithError(err).Error("Configuration reload failed, keeping current configuration")
+			}
+		}
+	}
+}
+
+// watchDirs returns unique directories of paths,
+// directories are watched instead of files to survive symlink swaps
+func watchDirs(paths []string) []string {
+	seen := make(map[string]struct{})
+	var dirs []string
+	for _, p := range paths {
+		dir := p
+		if st, err := os.Stat(p); err != nil || !st.IsDir() {
+			dir = filepath.Dir(p)
+		}
+		if _, ok := seen[dir]; !ok {
+			seen[dir] = struct{}{}
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,104 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+
+	"github.com/shuvava/go-ota-svc-common/config"
+)
+
+func TestWatcher(t *testing.T) {
+	const initial = `
+server:
+  name: svc
+mongo:
+  connectionString: mongodb://localhost/db
+log:
+  level: info
+`
+	t.Run("should publish changes of file updated through symlink swap", func(t *testing.T) {
+		// emulate k8s ConfigMap volume layout: config.yaml -> ..data/config.yaml, ..data -> ..v1
+		dir := t.TempDir()
+		for _, v := range []string{"..v1", "..v2"} {
+			if err := os.Mkdir(filepath.Join(dir, v), 0o700); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeFile(t, filepath.Join(dir, "..v1"), "config.yaml", initial)
+		writeFile(t, filepath.Join(dir, "..v2"), "config.yaml", initial+"  # updated\n")
+		if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")); err != nil {
+			t.Fatal(err)
+		}
+		// change log level in new version
+		writeFile(t, filepath.Join(dir, "..v2"), "config.yaml", `
+server:
+  name: svc
+mongo:
+  connectionString: mongodb://localhost/db
+log:
+  level: debug
+`)
+
+		log := logger.NewNopLogger()
+		w, err := config.NewWatcher[testConfig](log, config.WithFiles(filepath.Join(dir, "config.yaml")))
+		if err != nil {
+			t.Fatalf("NewWatcher returned error: %v", err)
+		}
+		w.ReloadDelay = 10 * time.Millisecond
+		config.ReloadLogLevel(w, func(c *testConfig) config.LogConfig { return c.Log }, log)
+		changed := make(chan config.LogConfig, 1)
+		config.OnChange(w, func(c *testConfig) config.LogConfig { return c.Log },
+			func(_, next config.LogConfig) { changed <- next })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = w.Run(ctx) }()
+		time.Sleep(50 * time.Millisecond)
+
+		tmp := filepath.Join(dir, "..data_tmp")
+		if err = os.Symlink("..v2", tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case cfg := <-changed:
+			if cfg.Level != "debug" {
+				t.Errorf("got level %s, expected debug", cfg.Level)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("change event was not published")
+		}
+		if w.Current().Log.Level != "debug" {
+			t.Error("current configuration was not updated")
+		}
+		if log.GetLevel() != logger.DebugLevel {
+			t.Error("log level was not changed")
+		}
+	})
+	t.Run("should keep current configuration if new one is invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeFile(t, dir, "config.yaml", initial)
+		w, err := config.NewWatcher[testConfig](logger.NewNopLogger(), config.WithFiles(path))
+		if err != nil {
+			t.Fatalf("NewWatcher returned error: %v", err)
+		}
+		writeFile(t, dir, "config.yaml", "log:\n  level: verbose\n")
+		if err = w.Reload(); err == nil {
+			t.Error("Reload should fail on invalid configuration")
+		}
+		if w.Current().Log.Level != "info" {
+			t.Error("current configuration should not be changed")
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package data
 
+const maxNamespaceLength = 255
+
 // Namespace is object namespace
 type Namespace string
 
@@ -11,3 +13,20 @@ func NewNamespace(id string) Namespace {
 
 	return Namespace(id)
 }
+
+// ValidNamespace verifies if ns is valid namespace
+// (not empty string of letters, digits and "-._:" symbols)
+func ValidNamespace(ns string) bool {
+	if ns == "" || len(ns) > maxNamespaceLength {
+		return false
+	}
+	for _, c := range ns {
+		if !('0' <= c && c <= '9') &&
+			!('a' <= c && c <= 'z') &&
+			!('A' <= c && c <= 'Z') &&
+			c != '-' && c != '.' && c != '_' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
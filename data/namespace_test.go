@@ -0,0 +1,25 @@
+package data_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+func TestValidNamespace(t *testing.T) {
+	cases := map[string]bool{
+		"default": true,
+		"urn:here-ota:namespace:4bf92f35-77b3-4da6-a3ce-929d0e0e4736": true,
+		"":         false,
+		"bad ns":   false,
+		"ns/child": false,
+	}
+	for ns, expected := range cases {
+		t.Run(fmt.Sprintf("namespace '%s'", ns), func(t *testing.T) {
+			if got := data.ValidNamespace(ns); got != expected {
+				t.Errorf("got %v, want %v", got, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shuvava/go-logging/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// defaultDrainTimeout is max time to wait for in-flight operations of replaced connection
+const defaultDrainTimeout = 30 * time.Second
+
+// connection is mongo client with its database name and pool monitor,
+// it counts operations using client to close replaced client after they complete
+type connection struct {
+	client   *mongo.Client
+	pool     *poolMonitor
+	database string
+
+	mu      sync.Mutex
+	active  int
+	retired bool
+	idle    chan struct{}
+}
+
+// acquire registers operation using connection, it fails if connection was replaced
+func (c *connection) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.retired {
+		return false
+	}
+	c.active++
+	return true
+}
+
+// release unregisters completed operation
+func (c *connection) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	if c.retired && c.active == 0 {
+		close(c.idle)
+	}
+}
+
+// retire rejects new operations, returned channel is closed when all acquired operations complete
+func (c *connection) retire() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retired = true
+	if c.active == 0 {
+		close(c.idle)
+	}
+	return c.idle
+}
+
+// connect validates connection string and creates new mongo client
+func connect(ctx context.Context, log logger.Logger, connectString string) (*connection, error) {
+	cs, err := connstring.ParseAndValidate(connectString)
+	if err != nil {
+		log.WithError(err).
+			Error("Connection string validation failed")
+		return nil, apperrors.NewAppError(
+			apperrors.ErrorDbConnection,
+			fmt.Sprintf("Connection string validation failed (%v)", err))
+	}
+
+	ctxConnect, cancel := context.WithTimeout(ctx, defaultMongoTimeout)
+	defer cancel()
+	opts := options.Client().ApplyURI(connectString)
+	pool := newPoolMonitor(opts.MaxPoolSize)
+	opts.SetPoolMonitor(pool.monitor())
+	client, err := mongo.Connect(ctxConnect, opts)
+	if err != nil {
+		return nil, apperrors.CreateErrorAndLogIt(log,
+			apperrors.ErrorDbConnection,
+			"Creating NewClient failed", err)
+	}
+
+	return &connection{
+		client:   client,
+		pool:     pool,
+		database: cs.Database,
+		idle:     make(chan struct{}),
+	}, nil
+}
+
+// Reconnect replaces connection to database with new one (ex. after credentials rotation),
+// new connection is used only if primary is reachable, old one is closed after in-flight operations complete
+// (or drain timeout expires)
+func (db *Db) Reconnect(ctx context.Context, connectString string) error {
+	log := db.logger(ctx)
+	conn, err := connect(ctx, log, connectString)
+	if err != nil {
+		return err
+	}
+	ctxPing, cancel := context.WithTimeout(ctx, db.Timeout())
+	defer cancel()
+	if err = conn.client.Ping(ctxPing, readpref.Primary()); err != nil {
+		_ = conn.client.Disconnect(ctx)
+		return apperrors.CreateErrorAndLogIt(log,
+			apperrors.ErrorDbConnection,
+			"New connection to DB failed", err)
+	}
+
+	old := db.conn.Swap(conn)
+	log.Info("DB connection replaced")
+	go func() {
+		ctxDrain, cancel := context.WithTimeout(context.Background(), defaultDrainTimeout)
+		defer cancel()
+		select {
+		case <-old.retire():
+		case <-ctxDrain.Done():
+			log.Warn("In-flight operations of replaced DB connection did not complete in time")
+		}
+		if err := old.client.Disconnect(ctxDrain); err != nil {
+			log.WithError(err).Warn("Failed to close replaced DB connection")
+		}
+	}()
+	return nil
+}
+
+// SetTimeout changes timeout of db operations, it is safe to call while Db is in use (ex. on configuration reload),
+// zero or negative timeout restores default one
+func (db *Db) SetTimeout(timeout time.Duration) {
+	db.timeout.Store(int64(timeout))
+}
+
+// Timeout returns timeout of db operations
+func (db *Db) Timeout() time.Duration {
+	if t := db.timeout.Load(); t > 0 {
+		return time.Duration(t)
+	}
+	return defaultMongoTimeout
+}
+
+// client returns current mongo client
+func (db *Db) client() *mongo.Client {
+	return db.conn.Load().client
+}
+
+// acquire returns current connection registered as used by operation,
+// release should be called when operation completes
+func (db *Db) acquire() (*connection, func()) {
+	for {
+		conn := db.conn.Load()
+		if conn.acquire() {
+			return conn, conn.release
+		}
+		// connection was replaced by Reconnect, load new one
+	}
+}
+
+// collection rebinds collection obtained before Reconnect to current client,
+// release should be called when operation using collection completes
+func (db *Db) collection(coll *mongo.Collection) (*mongo.Collection, func()) {
+	conn, release := db.acquire()
+	if coll.Database().Client() == conn.client {
+		return coll, release
+	}
+	return conn.client.
+		Database(coll.Database().Name()).
+		Collection(coll.Name()), release
+}
@@ -2,7 +2,9 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/shuvava/go-logging/logger"
@@ -14,7 +16,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shuvava/go-ota-svc-common/tracing"
 )
 
 const defaultMongoTimeout = 5 * time.Second
@@ -48,15 +52,31 @@ type BaseMongoRepository interface {
 	CollectionStats(ctx context.Context, coll *mongo.Collection) (*CollectionStats, error)
 }
 
+// ExtendedMongoRepository is optional MongoDb repository functionality implemented by Db,
+// it is kept separately to not break other implementations of BaseMongoRepository
+type ExtendedMongoRepository interface {
+	BaseMongoRepository
+	// FindWithOptions finds documents using filter and find options (skip, limit, sort, projection)
+	FindWithOptions(ctx context.Context, coll *mongo.Collection, filter interface{}, opts *options.FindOptions, docs interface{}) error
+	// FindOneAndUpdate atomically updates single document looked up by filter and decodes it
+	FindOneAndUpdate(ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{}, opts *options.FindOneAndUpdateOptions, document interface{}) error
+	// ReplaceOneVersioned replaces a single document looked up by filter if its version equals expected one
+	ReplaceOneVersioned(ctx context.Context, coll *mongo.Collection, filter interface{}, version int64, document interface{}) error
+	// UpdateOneVersioned updates a single document looked up by filter if its version equals expected one
+	UpdateOneVersioned(ctx context.Context, coll *mongo.Collection, filter interface{}, version int64, update bson.D) error
+	// CreateIndexes creates indexes of collection if they do not exist
+	CreateIndexes(ctx context.Context, coll *mongo.Collection, indexes []mongo.IndexModel) error
+}
+
 // DBResult DB result from custom queries
 type DBResult map[string]interface{}
 
 // Db service managing connection to MongoDb instance
 type Db struct {
-	client   *mongo.Client
-	log      logger.Logger
-	database string
-	Timeout  time.Duration
+	conn    atomic.Pointer[connection]
+	log     logger.Logger
+	tracer  trace.Tracer
+	timeout atomic.Int64
 	BaseMongoRepository
 }
 
@@ -75,58 +95,85 @@ type CollectionStats struct {
 // NewMongoDB create a new Db instance, with the connection URI provided
 func NewMongoDB(ctx context.Context, lgr logger.Logger, connectString string) (*Db, error) {
 	log := lgr.SetOperation("Db").WithContext(ctx)
-	cs, err := connstring.ParseAndValidate(connectString)
+	conn, err := connect(ctx, log, connectString)
 	if err != nil {
-		log.WithError(err).
-			Error("Connection string validation failed")
-		return nil, apperrors.NewAppError(
-			apperrors.ErrorDbConnection,
-			fmt.Sprintf("Connection string validation failed (%v)", err))
-	}
-
-	ctxConnect, cancel := context.WithTimeout(ctx, defaultMongoTimeout)
-	defer cancel()
-	client, err := mongo.Connect(ctxConnect, options.Client().ApplyURI(connectString))
-	if err != nil {
-		return nil, apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbConnection,
-			"Creating NewClient failed", err)
+		return nil, err
 	}
 
-	inst := Db{
-		client:   client,
-		database: cs.Database,
-		log:      log,
-		Timeout:  defaultMongoTimeout,
+	inst := &Db{
+		log:    log,
+		tracer: tracing.Tracer(),
 	}
-	return &inst, nil
+	inst.conn.Store(conn)
+	return inst, nil
 }
 
 // Disconnect close sockets to DB
 func (db *Db) Disconnect(ctx context.Context) error {
-	log := db.log.WithContext(ctx)
-	ctxDisc, cancel := context.WithTimeout(ctx, db.Timeout)
+	log := db.logger(ctx)
+	ctxDisc, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
-	if err := db.client.Disconnect(ctxDisc); err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Disconnect from DB failed", err)
+	if err := db.client().Disconnect(ctxDisc); err != nil {
+		return operationError(ctx, log, "Disconnect from DB failed", err)
 	}
 	return nil
 }
 
 // Ping check connection to database
-func (db *Db) Ping(ctx context.Context) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) Ping(ctx context.Context) (err error) {
+	ctx, span := db.startSpan(ctx, nil, "ping")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
+	defer log.TrackFuncTime(time.Now())
+	conn, release := db.acquire()
+	defer release()
+	ctxPing, cancel := context.WithTimeout(ctx, db.Timeout())
+	defer cancel()
+	return conn.client.Ping(ctxPing, readpref.Primary())
+}
+
+// HelloResult is replica set state returned by hello command
+type HelloResult struct {
+	// IsWritablePrimary is true if connected server is replica set primary
+	IsWritablePrimary bool `bson:"isWritablePrimary" json:"isWritablePrimary"`
+	// SetName is name of replica set
+	SetName string `bson:"setName" json:"setName,omitempty"`
+	// Primary is address of replica set primary
+	Primary string `bson:"primary" json:"primary,omitempty"`
+	// Hosts are addresses of replica set members
+	Hosts []string `bson:"hosts" json:"hosts,omitempty"`
+}
+
+// Hello returns replica set state of server selected by read preference
+func (db *Db) Hello(ctx context.Context, rp *readpref.ReadPref) (_ *HelloResult, err error) {
+	ctx, span := db.startSpan(ctx, nil, "hello")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
-	ctxPing, cancel := context.WithTimeout(ctx, db.Timeout)
+
+	conn, release := db.acquire()
+	defer release()
+	ctxHello, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
-	return db.client.Ping(ctxPing, readpref.Primary())
+	var res HelloResult
+	err = conn.client.Database("admin").
+		RunCommand(ctxHello, bson.D{{Key: "hello", Value: 1}}, options.RunCmd().SetReadPreference(rp)).
+		Decode(&res)
+	if err != nil {
+		return nil, operationError(ctx, log, "Failed to run hello command", err)
+	}
+	return &res, nil
+}
+
+// PoolStats returns connection pool usage statistics
+func (db *Db) PoolStats() PoolStats {
+	return db.conn.Load().pool.stats()
 }
 
 // Database return current mongo database
 func (db *Db) Database() *mongo.Database {
-	return db.client.Database(db.database)
+	conn := db.conn.Load()
+	return conn.client.Database(conn.database)
 }
 
 // GetCollection returns reference to mongo.Collection (table)
@@ -135,55 +182,70 @@ func (db *Db) GetCollection(name string) *mongo.Collection {
 }
 
 // InsertOne executes an insert command to insert a single document into the collection.
-func (db *Db) InsertOne(ctx context.Context, coll *mongo.Collection, document interface{}) (string, error) {
-	log := db.log.WithContext(ctx)
+func (db *Db) InsertOne(ctx context.Context, coll *mongo.Collection, document interface{}) (_ string, err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "insert")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
-	ctxIns, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxIns, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	// Attempt to persist a new document
 	res, err := coll.InsertOne(ctxIns, document)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", apperrors.CreateError(apperrors.ErrorDbAlreadyExist,
+			"document already exists", err)
+	}
 	if err != nil {
-		return "", apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to add new DB record", err)
+		return "", operationError(ctx, log, "Failed to add new DB record", err)
 	}
 
 	// Return the newly generated object ID of the persisted document
-	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		return oid.Hex(), nil
+	}
+	return fmt.Sprint(res.InsertedID), nil
 }
 
 // Count returns count of documents looked up by filter
-func (db *Db) Count(ctx context.Context, coll *mongo.Collection, filter interface{}) (int64, error) {
-	log := db.log.WithContext(ctx)
+func (db *Db) Count(ctx context.Context, coll *mongo.Collection, filter interface{}) (_ int64, err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "count")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxCnt, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxCnt, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	cnt, err := coll.CountDocuments(ctxCnt, filter)
 	if err != nil {
-		return 0, apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to get count of DB records", err)
+		return 0, operationError(ctx, log, "Failed to get count of DB records", err)
 	}
 
 	return cnt, nil
 }
 
 // GetOne returns document looked up by filter, or error
-func (db *Db) GetOne(ctx context.Context, coll *mongo.Collection, filter interface{}, document interface{}) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) GetOne(ctx context.Context, coll *mongo.Collection, filter interface{}, document interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "findOne")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxGet, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxGet, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	// Initialize a projection
 	projection := bson.M{}
 
 	opt := options.FindOne().SetProjection(projection)
-	err := coll.FindOne(ctxGet, filter, opt).Decode(document)
+	err = coll.FindOne(ctxGet, filter, opt).Decode(document)
 	if err == nil {
 		return nil
 	}
@@ -193,14 +255,12 @@ func (db *Db) GetOne(ctx context.Context, coll *mongo.Collection, filter interfa
 		return apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "document not found")
 	}
 	// Otherwise, return the provided error
-	return apperrors.CreateErrorAndLogIt(log,
-		apperrors.ErrorDbOperation,
-		"Failed to get count of DB records", err)
+	return operationError(ctx, log, "Failed to get count of DB records", err)
 }
 
 // GetOneByID returns document looked up by id, or error
 func (db *Db) GetOneByID(ctx context.Context, coll *mongo.Collection, id string, document interface{}) error {
-	log := db.log.WithContext(ctx)
+	log := db.logger(ctx)
 	oid, err := parseObjectID(id)
 	if err != nil {
 		return apperrors.CreateErrorAndLogIt(log,
@@ -215,19 +275,21 @@ func (db *Db) GetOneByID(ctx context.Context, coll *mongo.Collection, id string,
 }
 
 // Delete deletes a stored document(s) looked up by provided filter
-func (db *Db) Delete(ctx context.Context, coll *mongo.Collection, filter interface{}) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) Delete(ctx context.Context, coll *mongo.Collection, filter interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "delete")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxDel, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxDel, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	// Try to delete asset from database
 	result, err := coll.DeleteMany(ctxDel, filter)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed no delete record from DB", err)
+		return operationError(ctx, log, "Failed no delete record from DB", err)
 	}
 
 	// If no asset was deleted, then an asset with this particular ID was not found
@@ -243,7 +305,7 @@ func (db *Db) Delete(ctx context.Context, coll *mongo.Collection, filter interfa
 
 // DeleteByID deletes a stored document
 func (db *Db) DeleteByID(ctx context.Context, coll *mongo.Collection, id string) error {
-	log := db.log.WithContext(ctx)
+	log := db.logger(ctx)
 	oid, err := parseObjectID(id)
 	if err != nil {
 		return apperrors.CreateErrorAndLogIt(log,
@@ -259,48 +321,54 @@ func (db *Db) DeleteByID(ctx context.Context, coll *mongo.Collection, id string)
 
 // Find returns all documents matching to the filter
 func (db *Db) Find(ctx context.Context, coll *mongo.Collection, filter interface{}, docs interface{}) error {
-	log := db.log.WithContext(ctx)
+	// Initialize a projection
+	projection := bson.M{}
+
+	return db.FindWithOptions(ctx, coll, filter, options.Find().SetProjection(projection), docs)
+}
+
+// FindWithOptions finds documents using filter and find options (skip, limit, sort, projection)
+func (db *Db) FindWithOptions(ctx context.Context, coll *mongo.Collection, filter interface{}, opts *options.FindOptions, docs interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "find")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxFind, cancelFind := context.WithTimeout(ctx, db.Timeout)
+	ctxFind, cancelFind := context.WithTimeout(ctx, db.Timeout())
 	defer cancelFind()
 
-	// Initialize a projection
-	projection := bson.M{}
-
-	opt := options.Find().SetProjection(projection)
-	cur, err := coll.Find(ctxFind, filter, opt)
+	cur, err := coll.Find(ctxFind, filter, opts)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to find DB records", err)
+		return operationError(ctx, log, "Failed to find DB records", err)
 	}
 
-	ctxCur, cancelCur := context.WithTimeout(ctx, db.Timeout)
+	ctxCur, cancelCur := context.WithTimeout(ctx, db.Timeout())
 	defer cancelCur()
 	err = cur.All(ctxCur, docs)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to fetch DB records", err)
+		return operationError(ctx, log, "Failed to fetch DB records", err)
 	}
 
 	return nil
 }
 
 // ReplaceOne replace a single document looked up by filter
-func (db *Db) ReplaceOne(ctx context.Context, coll *mongo.Collection, filter interface{}, document interface{}) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) ReplaceOne(ctx context.Context, coll *mongo.Collection, filter interface{}, document interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "replace")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxUpd, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxUpd, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	res, err := coll.ReplaceOne(ctxUpd, filter, document)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to replace DB record", err)
+		return operationError(ctx, log, "Failed to replace DB record", err)
 	}
 	if res.ModifiedCount != 1 {
 		return apperrors.NewAppError(
@@ -312,11 +380,15 @@ func (db *Db) ReplaceOne(ctx context.Context, coll *mongo.Collection, filter int
 }
 
 // Aggregate execute custom aggregate query
-func (db *Db) Aggregate(ctx context.Context, coll *mongo.Collection, pipe interface{}, opts *options.AggregateOptions, documents interface{}) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) Aggregate(ctx context.Context, coll *mongo.Collection, pipe interface{}, opts *options.AggregateOptions, documents interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "aggregate")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxAgg, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxAgg, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	if opts == nil {
@@ -325,33 +397,31 @@ func (db *Db) Aggregate(ctx context.Context, coll *mongo.Collection, pipe interf
 
 	data, err := coll.Aggregate(ctxAgg, pipe, opts)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to run DB query", err)
+		return operationError(ctx, log, "Failed to run DB query", err)
 	}
 	err = data.All(ctxAgg, documents)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"failed to decode results", err)
+		return operationError(ctx, log, "failed to decode results", err)
 	}
 
 	return nil
 }
 
 // UpdateOne updates a fields in single document looked up by filter
-func (db *Db) UpdateOne(ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{}) error {
-	log := db.log.WithContext(ctx)
+func (db *Db) UpdateOne(ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "update")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxUpd, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxUpd, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 
 	res, err := coll.UpdateOne(ctxUpd, filter, update)
 	if err != nil {
-		return apperrors.CreateErrorAndLogIt(log,
-			apperrors.ErrorDbOperation,
-			"Failed to update DB record", err)
+		return operationError(ctx, log, "Failed to update DB record", err)
 	}
 	if res.MatchedCount == 0 {
 		return apperrors.NewAppError(
@@ -367,21 +437,79 @@ func (db *Db) UpdateOne(ctx context.Context, coll *mongo.Collection, filter inte
 	return nil
 }
 
+// FindOneAndUpdate atomically updates single document looked up by filter and decodes it
+// (document before update unless opts sets ReturnDocument to options.After)
+func (db *Db) FindOneAndUpdate(ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{},
+	opts *options.FindOneAndUpdateOptions, document interface{}) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "findAndModify")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
+	defer log.TrackFuncTime(time.Now())
+
+	ctxUpd, cancel := context.WithTimeout(ctx, db.Timeout())
+	defer cancel()
+
+	err = coll.FindOneAndUpdate(ctxUpd, filter, update, opts).Decode(document)
+	switch {
+	case err == nil:
+		return nil
+	case err == mongo.ErrNoDocuments:
+		return apperrors.NewAppError(apperrors.ErrorDbNoDocumentFound, "document not found")
+	case mongo.IsDuplicateKeyError(err):
+		// concurrent upsert of the same document
+		return apperrors.CreateError(apperrors.ErrorDbAlreadyExist, "document already exists", err)
+	}
+	return operationError(ctx, log, "Failed to update DB record", err)
+}
+
 // CollectionStats returns general statistics about mongodb collection
-func (db *Db) CollectionStats(ctx context.Context, coll *mongo.Collection) (*CollectionStats, error) {
-	log := db.log.WithContext(ctx)
+func (db *Db) CollectionStats(ctx context.Context, coll *mongo.Collection) (_ *CollectionStats, err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "collStats")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
 	defer log.TrackFuncTime(time.Now())
 
-	ctxSts, cancel := context.WithTimeout(ctx, db.Timeout)
+	ctxSts, cancel := context.WithTimeout(ctx, db.Timeout())
 	defer cancel()
 	result := coll.Database().RunCommand(ctxSts, bson.M{"collStats": coll.Name()})
 	var doc CollectionStats
-	if err := result.Decode(&doc); err != nil {
+	if err = result.Decode(&doc); err != nil {
 		return nil, err
 	}
 	return &doc, nil
 }
 
+// CreateIndexes creates indexes of collection if they do not exist
+func (db *Db) CreateIndexes(ctx context.Context, coll *mongo.Collection, indexes []mongo.IndexModel) (err error) {
+	coll, release := db.collection(coll)
+	defer release()
+	ctx, span := db.startSpan(ctx, coll, "createIndexes")
+	defer func() { tracing.EndSpan(span, err) }()
+	log := db.logger(ctx)
+
+	ctxIdx, cancel := context.WithTimeout(ctx, db.Timeout())
+	defer cancel()
+
+	if _, err = coll.Indexes().CreateMany(ctxIdx, indexes); err != nil {
+		return operationError(ctx, log, "Failed to create DB indexes", err)
+	}
+	return nil
+}
+
+// operationError creates error of failed db operation, ErrorDbTimeout is returned
+// if operation timeout (Db.Timeout()) fired before the deadline of caller context
+func operationError(ctx context.Context, log logger.Logger, descr string, err error) error {
+	code := apperrors.AppErrorCode(apperrors.ErrorDbOperation)
+	if (errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)) && ctx.Err() == nil {
+		code = apperrors.ErrorDbTimeout
+	}
+	return apperrors.CreateErrorAndLogIt(log, code, descr, err)
+}
+
 // parseObjectID is a helper to parse a string assetID into a MongoDB-format ObjectID
 func parseObjectID(assetID string) (primitive.ObjectID, error) {
 	oid, err := primitive.ObjectIDFromHex(assetID)
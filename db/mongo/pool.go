@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// defaultMaxPoolSize is default max size of mongo driver connection pool
+const defaultMaxPoolSize = 100
+
+// PoolStats connection pool usage statistics
+type PoolStats struct {
+	// Open is count of open connections
+	Open int64 `json:"open"`
+	// InUse is count of connections checked out of pool
+	InUse int64 `json:"inUse"`
+	// MaxPoolSize is max count of connections per server
+	MaxPoolSize uint64 `json:"maxPoolSize"`
+}
+
+// poolMonitor tracks connection pool usage from driver events
+type poolMonitor struct {
+	open        atomic.Int64
+	inUse       atomic.Int64
+	maxPoolSize uint64
+}
+
+func newPoolMonitor(maxPoolSize *uint64) *poolMonitor {
+	m := &poolMonitor{maxPoolSize: defaultMaxPoolSize}
+	if maxPoolSize != nil {
+		m.maxPoolSize = *maxPoolSize
+	}
+	return m
+}
+
+func (m *poolMonitor) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			switch evt.Type {
+			case event.ConnectionCreated:
+				m.open.Add(1)
+			case event.ConnectionClosed:
+				m.open.Add(-1)
+			case event.GetSucceeded:
+				m.inUse.Add(1)
+			case event.ConnectionReturned:
+				m.inUse.Add(-1)
+			}
+		},
+	}
+}
+
+func (m *poolMonitor) stats() PoolStats {
+	return PoolStats{
+		Open:        m.open.Load(),
+		InUse:       m.inUse.Load(),
+		MaxPoolSize: m.maxPoolSize,
+	}
+}
@@ -0,0 +1,35 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/shuvava/go-logging/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shuvava/go-ota-svc-common/tracing"
+)
+
+// startSpan starts child span of db operation on collection
+func (db *Db) startSpan(ctx context.Context, coll *mongo.Collection, operation string) (context.Context, trace.Span) {
+	ctx, span := db.tracer.Start(ctx, "mongo."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongoDB,
+			semconv.DBName(db.conn.Load().database),
+			semconv.DBOperation(operation),
+		))
+	if coll != nil {
+		span.SetAttributes(semconv.DBMongoDBCollection(coll.Name()))
+	}
+	if ns := logger.GetTenantID(ctx); ns != "" {
+		span.SetAttributes(tracing.AttributeNamespace.String(ns))
+	}
+	return ctx, span
+}
+
+// logger returns db logger enriched with request context and trace ids
+func (db *Db) logger(ctx context.Context) logger.Logger {
+	return tracing.WithLogger(ctx, db.log)
+}
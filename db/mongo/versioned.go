@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// VersionField is name of document field used for optimistic concurrency control
+const VersionField = "version"
+
+// VersionFilter adds expected document version condition to filter
+func VersionFilter(filter interface{}, version int64) bson.D {
+	return bson.D{{Key: "$and", Value: bson.A{
+		filter,
+		bson.D{{Key: VersionField, Value: version}},
+	}}}
+}
+
+// ReplaceOneVersioned replaces a single document looked up by filter if its version equals expected one,
+// new document should contain incremented version
+func (db *Db) ReplaceOneVersioned(ctx context.Context, coll *mongo.Collection, filter interface{}, version int64, document interface{}) error {
+	err := db.ReplaceOne(ctx, coll, VersionFilter(filter, version), document)
+	return db.versionConflict(ctx, coll, filter, err)
+}
+
+// VersionUpdate adds increment of document version to update,
+// version increment is merged into existing $inc operator (bson.D or bson.M)
+func VersionUpdate(update bson.D) (bson.D, error) {
+	upd := make(bson.D, 0, len(update)+1)
+	found := false
+	for _, op := range update {
+		if op.Key == "$inc" {
+			switch incDoc := op.Value.(type) {
+			case bson.D:
+				op.Value = append(append(bson.D{}, incDoc...), bson.E{Key: VersionField, Value: int64(1)})
+			case bson.M:
+				op.Value = mergeInc(incDoc)
+			case map[string]interface{}:
+				op.Value = mergeInc(incDoc)
+			default:
+				return nil, apperrors.NewAppError(apperrors.ErrorDataValidation,
+					fmt.Sprintf("unsupported type %T of $inc operator", op.Value))
+			}
+			found = true
+		}
+		upd = append(upd, op)
+	}
+	if !found {
+		upd = append(upd, bson.E{Key: "$inc", Value: bson.D{{Key: VersionField, Value: int64(1)}}})
+	}
+	return upd, nil
+}
+
+// mergeInc returns copy of $inc document with version increment
+func mergeInc(inc map[string]interface{}) bson.M {
+	m := make(bson.M, len(inc)+1)
+	for k, v := range inc {
+		m[k] = v
+	}
+	m[VersionField] = int64(1)
+	return m
+}
+
+// UpdateOneVersioned updates a single document looked up by filter if its version equals expected one,
+// version of document is incremented
+func (db *Db) UpdateOneVersioned(ctx context.Context, coll *mongo.Collection, filter interface{}, version int64, update bson.D) error {
+	upd, err := VersionUpdate(update)
+	if err != nil {
+		return err
+	}
+	err = db.UpdateOne(ctx, coll, VersionFilter(filter, version), upd)
+	return db.versionConflict(ctx, coll, filter, err)
+}
+
+// versionConflict converts ErrorDbNoDocumentFound to ErrorDbVersionConflict if document still exists
+func (db *Db) versionConflict(ctx context.Context, coll *mongo.Collection, filter interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+	if apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDbNoDocumentFound {
+		return err
+	}
+	cnt, cntErr := db.Count(ctx, coll, filter)
+	if cntErr != nil || cnt == 0 {
+		return err
+	}
+	return apperrors.NewAppError(apperrors.ErrorDbVersionConflict,
+		"document was modified concurrently (version mismatch)")
+}
@@ -0,0 +1,54 @@
+package mongo_test
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+	"github.com/shuvava/go-ota-svc-common/db/mongo"
+)
+
+func TestVersionUpdate(t *testing.T) {
+	t.Run("should merge version increment into existing $inc", func(t *testing.T) {
+		tests := []bson.D{
+			{{Key: "$inc", Value: bson.D{{Key: "count", Value: 1}}}},
+			{{Key: "$inc", Value: bson.M{"count": 1}}},
+			{{Key: "$inc", Value: map[string]interface{}{"count": 1}}},
+		}
+		for _, update := range tests {
+			upd, err := mongo.VersionUpdate(append(bson.D{{Key: "$set", Value: bson.M{"name": "a"}}}, update...))
+			if err != nil {
+				t.Fatalf("VersionUpdate returned error: %v", err)
+			}
+			if len(upd) != 2 || upd[1].Key != "$inc" {
+				t.Fatalf("got update %v, expected single $inc", upd)
+			}
+			b, err := bson.Marshal(bson.D{upd[1]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var inc struct {
+				Inc map[string]int64 `bson:"$inc"`
+			}
+			if err = bson.Unmarshal(b, &inc); err != nil {
+				t.Fatal(err)
+			}
+			if len(inc.Inc) != 2 || inc.Inc["count"] != 1 || inc.Inc[mongo.VersionField] != 1 {
+				t.Errorf("got $inc %v, expected count and version increments", inc.Inc)
+			}
+		}
+	})
+	t.Run("should add $inc if update has none", func(t *testing.T) {
+		upd, err := mongo.VersionUpdate(bson.D{{Key: "$set", Value: bson.M{"name": "a"}}})
+		if err != nil || len(upd) != 2 || upd[1].Key != "$inc" {
+			t.Errorf("got update %v, error %v", upd, err)
+		}
+	})
+	t.Run("should reject unsupported $inc value", func(t *testing.T) {
+		_, err := mongo.VersionUpdate(bson.D{{Key: "$inc", Value: []int{1}}})
+		if err == nil || apperrors.ToAppError(err).ErrorCode != apperrors.ErrorDataValidation {
+			t.Errorf("got %v, expected %s", err, apperrors.ErrorDataValidation)
+		}
+	})
+}
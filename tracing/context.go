@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/shuvava/go-logging/logger"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shuvava/go-ota-svc-common/data"
+)
+
+const (
+	// ContextKeyTraceID is traceId key for context,
+	// logger.Logger WithContext does not read it (use WithLogger to add trace id to logs)
+	ContextKeyTraceID = logger.ContextKey("traceId")
+
+	logFieldTraceID = "TraceID"
+	logFieldSpanID  = "SpanID"
+)
+
+// TraceID returns trace id of span stored in context or empty string
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// CorrelationID returns data.CorrelationID with the same bytes as trace id of span stored in context
+func CorrelationID(ctx context.Context) (data.CorrelationID, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return data.CorrelationIDNil, false
+	}
+	return data.CorrelationID(sc.TraceID()), true
+}
+
+// WithTraceID adds trace id of span stored in context as context value
+func WithTraceID(ctx context.Context) context.Context {
+	tid := TraceID(ctx)
+	if tid == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ContextKeyTraceID, tid)
+}
+
+// WithLogger returns logger with request context and trace/span ids fields
+// (trace id stored by WithTraceID is used if context has no span)
+func WithLogger(ctx context.Context, log logger.Logger) logger.Logger {
+	l := log.WithContext(ctx)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		if tid, ok := ctx.Value(ContextKeyTraceID).(string); ok && tid != "" {
+			return l.WithField(logFieldTraceID, tid)
+		}
+		return l
+	}
+	return l.WithField(logFieldTraceID, sc.TraceID().String()).
+		WithField(logFieldSpanID, sc.SpanID().String())
+}
@@ -0,0 +1,49 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shuvava/go-logging/logger"
+	"github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/shuvava/go-ota-svc-common/tracing"
+)
+
+func TestContext(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+	defer span.End()
+	traceID := span.SpanContext().TraceID().String()
+
+	t.Run("should return trace id of span", func(t *testing.T) {
+		if tid := tracing.TraceID(ctx); tid != traceID {
+			t.Errorf("got trace id %q, expected %q", tid, traceID)
+		}
+		if cid, ok := tracing.CorrelationID(ctx); !ok || strings.ReplaceAll(cid.String(), "-", "") != traceID {
+			t.Errorf("got correlation id %s, expected trace id %s", cid, traceID)
+		}
+		if tid := tracing.TraceID(context.Background()); tid != "" {
+			t.Errorf("got trace id %q without span", tid)
+		}
+	})
+	t.Run("should add trace id to logger", func(t *testing.T) {
+		for name, ctx := range map[string]context.Context{
+			"span":             ctx,
+			"context value":    context.WithValue(context.Background(), tracing.ContextKeyTraceID, traceID),
+			"trace id of span": tracing.WithTraceID(ctx),
+		} {
+			var buf bytes.Buffer
+			lgr := logger.NewLogrusLogger(logrus.InfoLevel)
+			lgr.SetOutput(&buf)
+			tracing.WithLogger(ctx, lgr).Info("message")
+			if !strings.Contains(buf.String(), "TraceID="+traceID) {
+				t.Errorf("%s: got log %q, expected trace id %s", name, buf.String(), traceID)
+			}
+		}
+	})
+}
@@ -0,0 +1,2 @@
+// Package tracing implements OpenTelemetry distributed tracing boilerplate
+package tracing
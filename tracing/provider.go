@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/shuvava/go-ota-svc-common/apperrors"
+)
+
+// DefaultSampleRatio is ratio of sampled root spans used if Config.SampleRatio is not set
+const DefaultSampleRatio = 1.0
+
+// Config is configuration of trace provider
+type Config struct {
+	// ServiceName is name of service reported in spans
+	ServiceName string
+	// ServiceVersion is version of service reported in spans
+	ServiceVersion string
+	// Endpoint is OTLP/HTTP collector endpoint host:port
+	Endpoint string
+	// Insecure disables TLS of connection to collector
+	Insecure bool
+	// SampleRatio is ratio of sampled root spans (all spans are sampled if it is not set),
+	// spans with sampled parent are always sampled
+	SampleRatio float64
+}
+
+// NewProvider creates trace provider exporting spans to OTLP endpoint
+// and sets it as global trace provider
+func NewProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, apperrors.CreateError(apperrors.ErrorGeneric, "failed to create OTLP exporter", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = DefaultSampleRatio
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(newResource(cfg)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	setGlobal(tp)
+	return tp, nil
+}
+
+// NewInMemoryProvider creates trace provider storing spans in memory
+// and sets it as global trace provider (should be used in tests)
+func NewInMemoryProvider(cfg Config) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exp),
+		sdktrace.WithResource(newResource(cfg)),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	setGlobal(tp)
+	return tp, exp
+}
+
+func newResource(cfg Config) *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	)
+}
+
+func setGlobal(tp *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
@@ -0,0 +1,82 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/shuvava/go-ota-svc-common/tracing"
+)
+
+func TestInMemoryProvider(t *testing.T) {
+	tp, exp := tracing.NewInMemoryProvider(tracing.Config{ServiceName: "test", ServiceVersion: "1.0.0"})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	t.Run("should export spans with error status", func(t *testing.T) {
+		exp.Reset()
+		_, span := tracing.Tracer().Start(context.Background(), "op")
+		tracing.EndSpan(span, errors.New("failed"))
+		spans := exp.GetSpans()
+		if len(spans) != 1 {
+			t.Fatalf("got %d spans, expected 1", len(spans))
+		}
+		s := spans[0]
+		if s.Name != "op" || s.Status.Code != codes.Error || s.Status.Description != "failed" || len(s.Events) != 1 {
+			t.Errorf("got span %+v", s)
+		}
+		if name, ok := s.Resource.Set().Value("service.name"); !ok || name.AsString() != "test" {
+			t.Errorf("got resource %v", s.Resource)
+		}
+	})
+	t.Run("should propagate trace context", func(t *testing.T) {
+		exp.Reset()
+		ctx, parent := tracing.Tracer().Start(context.Background(), "client")
+		h := http.Header{}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+		if h.Get("traceparent") == "" {
+			t.Fatalf("got headers %v, expected traceparent", h)
+		}
+		remote := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(h))
+		_, child := tracing.Tracer().Start(remote, "server")
+		tracing.EndSpan(child, nil)
+		parent.End()
+
+		spans := exp.GetSpans()
+		if len(spans) != 2 {
+			t.Fatalf("got %d spans, expected 2", len(spans))
+		}
+		if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() || !spans[0].Parent.IsRemote() ||
+			spans[0].SpanContext.TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("got span %+v, expected child of %v", spans[0], parent.SpanContext())
+		}
+		if spans[0].Status.Code != codes.Unset {
+			t.Errorf("got status %+v", spans[0].Status)
+		}
+	})
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("should sample all spans if sample ratio is not set", func(t *testing.T) {
+		tp, err := tracing.NewProvider(context.Background(), tracing.Config{ServiceName: "test", Endpoint: "localhost:1", Insecure: true})
+		if err != nil {
+			t.Fatalf("failed to create provider: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_ = tp.Shutdown(ctx)
+		}()
+		for i := 0; i < 10; i++ {
+			// spans are not ended to avoid export
+			if _, span := tp.Tracer("test").Start(context.Background(), "op"); !span.SpanContext().IsSampled() {
+				t.Fatalf("span %d is not sampled", i)
+			}
+		}
+	})
+}
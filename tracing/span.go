@@ -0,0 +1,32 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName is name of tracer used by library instrumentation
+	InstrumentationName = "github.com/shuvava/go-ota-svc-common"
+
+	// AttributeNamespace is span attribute key of OTA namespace
+	AttributeNamespace = attribute.Key("ota.namespace")
+	// AttributeRequestID is span attribute key of request correlation id
+	AttributeRequestID = attribute.Key("ota.request_id")
+)
+
+// Tracer returns library tracer from global trace provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// EndSpan records error (if any) and ends span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}